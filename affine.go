@@ -0,0 +1,196 @@
+package turtle_graphics
+
+// This file contains the instructions that scale, mirror or otherwise
+// transform the turtle's frame: the coordinate system in which the turtle
+// moves. Everything the turtle draws is transformed from its frame to the
+// canvas, so, for example, arcs become elliptical arcs if the frame is scaled
+// differently along each axis. Like the rest of the turtle's position, the
+// frame is saved and restored by PushPosition and PopPosition.
+
+import (
+	"fmt"
+	"math"
+)
+
+// A two-dimensional affine transformation, mapping each point (x, y) to
+// (XX*x + XY*y + X0, YX*x + YY*y + Y0). Note that the zero value maps every
+// point to the origin; use IdentityTransform for a transformation that
+// doesn't change anything.
+type AffineTransform struct {
+	XX, YX, XY, YY, X0, Y0 float64
+}
+
+// Returns the transformation that leaves every point unchanged.
+func IdentityTransform() AffineTransform {
+	return AffineTransform{XX: 1, YY: 1}
+}
+
+// Returns a transformation that rotates points counter-clockwise about the
+// origin by the given number of degrees, multiplies their distance from the
+// origin by the given scale, and then moves the origin to (x, y).
+func similarityTransform(x, y, degrees, scale float64) AffineTransform {
+	radians := degrees * math.Pi / 180.0
+	cos := math.Cos(radians) * scale
+	sin := math.Sin(radians) * scale
+	return AffineTransform{
+		XX: cos,
+		YX: sin,
+		XY: -sin,
+		YY: cos,
+		X0: x,
+		Y0: y,
+	}
+}
+
+// Returns the transformed position of the point (x, y).
+func (m AffineTransform) Apply(x, y float64) (float64, float64) {
+	return m.XX*x + m.XY*y + m.X0, m.YX*x + m.YY*y + m.Y0
+}
+
+// Transforms the vector (x, y), ignoring the translation.
+func (m AffineTransform) applyLinear(x, y float64) (float64, float64) {
+	return m.XX*x + m.XY*y, m.YX*x + m.YY*y
+}
+
+// Returns the transformation that applies other, followed by m.
+func (m AffineTransform) Multiply(other AffineTransform) AffineTransform {
+	x0, y0 := m.Apply(other.X0, other.Y0)
+	return AffineTransform{
+		XX: m.XX*other.XX + m.XY*other.YX,
+		YX: m.YX*other.XX + m.YY*other.YX,
+		XY: m.XX*other.XY + m.XY*other.YY,
+		YY: m.YX*other.XY + m.YY*other.YY,
+		X0: x0,
+		Y0: y0,
+	}
+}
+
+// Returns the transformation stored as the operands of an opTransform
+// instruction.
+func transformFromOperands(operands []float64) AffineTransform {
+	return AffineTransform{
+		XX: operands[0],
+		YX: operands[1],
+		XY: operands[2],
+		YY: operands[3],
+		X0: operands[4],
+		Y0: operands[5],
+	}
+}
+
+// Returns the factor by which the transformation scales areas, which is
+// negative if it mirrors them.
+func (m AffineTransform) determinant() float64 {
+	return m.XX*m.YY - m.XY*m.YX
+}
+
+// Returns the position of a point in the turtle's frame on the canvas the
+// turtle's instructions are carried out on.
+func (s *TurtleState) framePoint(x, y float64) (float64, float64) {
+	if s.position.transform == nil {
+		return x, y
+	}
+	return s.position.transform.Apply(x, y)
+}
+
+// Returns the transformation from the turtle's frame to the canvas its
+// instructions are carried out on. See Turtle.Transform.
+func (s *TurtleState) Transform() AffineTransform {
+	if s.position.transform == nil {
+		return IdentityTransform()
+	}
+	return *(s.position.transform)
+}
+
+// Returns the canvas the turtle's instructions were given, if c is the
+// canvas returned by frameCanvas.
+func (s *TurtleState) baseCanvas(c Canvas) Canvas {
+	if (s.frame != nil) && (c == Canvas(s.frame)) {
+		return s.frame.canvas
+	}
+	return c
+}
+
+// Returns the canvas that instructions should draw on: c, transformed from the
+// turtle's frame if it has one. A turtle's instructions are always carried out
+// on the same canvas, so the transformed canvas is reused until the frame
+// changes. Returns a canvas for the current frame even if c was returned by an
+// earlier call using a different frame.
+func (s *TurtleState) frameCanvas(c Canvas) Canvas {
+	c = s.baseCanvas(c)
+	m := s.position.transform
+	if m == nil {
+		return c
+	}
+	if (s.frame == nil) || (s.frame.m != *m) {
+		frame := newAffineCanvas(c, *m)
+		s.frame = &frame
+	}
+	return s.frame
+}
+
+// An instruction transforming the turtle's frame.
+type transformInstruction struct {
+	m AffineTransform
+}
+
+func (n *transformInstruction) String() string {
+	return fmt.Sprintf("Transform frame by [%f %f %f %f %f %f]", n.m.XX,
+		n.m.YX, n.m.XY, n.m.YY, n.m.X0, n.m.Y0)
+}
+
+func (n *transformInstruction) Apply(s *TurtleState, c Canvas) error {
+	// Express the transformation relative to the turtle's position and
+	// heading in its current frame, rather than the frame's origin.
+	x, y, angle := s.getPosition()
+	toTurtle := similarityTransform(x, y, angle, 1)
+	fromTurtle := similarityTransform(0, 0, -angle, 1).Multiply(
+		AffineTransform{XX: 1, YY: 1, X0: -x, Y0: -y})
+	m := s.Transform().Multiply(toTurtle.Multiply(n.m.Multiply(fromTurtle)))
+	s.position.transform = &m
+	// The turtle stays at the same position in its frame, but may have moved
+	// on the canvas.
+	s.moveTo(x, y)
+	if s.position.lineWidth <= 0 {
+		return nil
+	}
+	// Line widths are in the turtle's frame, so they need to be scaled again.
+	return s.frameCanvas(c).SetStyle(s.canvasStyle())
+}
+
+// Adds an instruction to transform the turtle's frame: the coordinate system
+// in which it moves. The transformation is relative to the turtle, as if the
+// turtle were at the origin with a heading of 0, so the X axis points in the
+// direction the turtle is facing and the Y axis points to its left. For
+// example, XX scales distances in the direction the turtle is facing, and X0
+// moves the frame forward, taking the turtle with it.
+//
+// Everything the turtle draws afterwards is transformed, including arcs, which
+// become elliptical arcs if the transformation doesn't scale uniformly, and
+// the shapes drawn by Stamp and Call. Line widths are scaled by the square
+// root of the factor by which areas are scaled, and dots remain circular. The
+// turtle's position, heading, and the coordinates passed to GoTo and similar
+// instructions are all in the transformed frame, as are the positions and
+// headings available from TurtleState. The frame is saved by PushPosition and
+// restored by PopPosition, and isn't affected by Home.
+func (t *Turtle) Transform(m AffineTransform) {
+	t.addOp(opTransform, nil, m.XX, m.YX, m.XY, m.YY, m.X0, m.Y0)
+}
+
+// Adds an instruction to scale the turtle's frame by sx in the direction the
+// turtle is facing, and by sy to its left. Unlike ScaleStep, this also scales
+// arcs, curves, text, dots, line widths and stamps, and scaling each direction
+// differently distorts the drawing. Equivalent to Transform with XX set to sx,
+// YY set to sy, and the other fields set to 0.
+func (t *Turtle) Scale(sx, sy float64) {
+	t.Transform(AffineTransform{XX: sx, YY: sy})
+}
+
+// Adds an instruction to mirror the turtle's frame across the direction the
+// turtle is facing, swapping left and right. Afterwards, turns to the left are
+// drawn as turns to the right and vice versa, like the '|' or '&' symbols used
+// by some L-systems. Mirroring twice restores the original frame. Equivalent
+// to Scale(1, -1).
+func (t *Turtle) Mirror() {
+	t.Scale(1, -1)
+}
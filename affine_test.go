@@ -0,0 +1,223 @@
+package turtle_graphics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTransformPushPop(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform func(t *Turtle)
+		// The points on the canvas reached by moving forward 1, turning left
+		// and moving forward 1 again in the transformed frame.
+		p1, p2 Point
+	}{
+		{"Scale", func(t *Turtle) { t.Scale(2, 3) }, Point{2, 0}, Point{2, 3}},
+		{"Mirror", func(t *Turtle) { t.Mirror() }, Point{1, 0}, Point{1, -1}},
+		{"Shear", func(t *Turtle) {
+			t.Transform(AffineTransform{XX: 1, XY: 1, YY: 1})
+		}, Point{1, 0}, Point{2, 1}},
+		{"Scale then mirror", func(t *Turtle) {
+			t.Scale(2, 3)
+			t.Mirror()
+		}, Point{2, 0}, Point{2, -3}},
+	}
+	for _, test := range tests {
+		// The transformation only applies until the position is popped, so
+		// the final move isn't transformed.
+		turtle := NewTurtle()
+		turtle.PushPosition()
+		test.transform(turtle)
+		turtle.MoveForward(1)
+		turtle.Turn(90)
+		turtle.MoveForward(1)
+		turtle.PopPosition()
+		turtle.Turn(-90)
+		turtle.MoveForward(1)
+		paths := getTestPaths(t, turtle, 0.01)
+		if len(paths) != 2 {
+			t.Errorf("%s: expected 2 paths, got %d: %v", test.name,
+				len(paths), paths)
+			continue
+		}
+		checkPathPoints(t, paths[0], []Point{{0, 0}, test.p1, test.p2})
+		checkPathPoints(t, paths[1], []Point{{0, 0}, {0, -1}})
+	}
+}
+
+func TestTransformNestedPushPop(t *testing.T) {
+	// Popping the inner position restores the outer transformation, rather
+	// than the untransformed frame.
+	turtle := NewTurtle()
+	turtle.PushPosition()
+	turtle.Scale(2, 2)
+	turtle.PushPosition()
+	turtle.Mirror()
+	turtle.Turn(90)
+	turtle.MoveForward(1)
+	turtle.PopPosition()
+	turtle.Turn(90)
+	turtle.MoveForward(1)
+	turtle.PopPosition()
+	turtle.MoveForward(1)
+	paths := getTestPaths(t, turtle, 0.01)
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 paths, got %d: %v", len(paths), paths)
+	}
+	checkPathPoints(t, paths[0], []Point{{0, 0}, {0, -2}})
+	checkPathPoints(t, paths[1], []Point{{0, 0}, {0, 2}})
+	checkPathPoints(t, paths[2], []Point{{0, 0}, {1, 0}})
+}
+
+func TestTransformRelativeToTurtle(t *testing.T) {
+	// The transformation is relative to the turtle's position and heading,
+	// and the turtle's position remains in the transformed frame.
+	turtle := NewTurtleWithOptions(TurtleOptions{X: 1, Y: 1, Heading: 90})
+	turtle.Scale(3, 1)
+	turtle.MoveForward(1)
+	turtle.Turn(-90)
+	turtle.MoveForward(1)
+	_, state := getFinalState(t, turtle)
+	if (math.Abs(state.x-2) > 1e-9) || (math.Abs(state.y-2) > 1e-9) ||
+		(headingDifference(state.heading, 0) > 1e-9) {
+		t.Errorf("Expected to end at (2, 2) in the frame with heading 0, "+
+			"got (%f, %f) with heading %f", state.x, state.y, state.heading)
+	}
+	// Rounding errors may split the path, so join it before checking.
+	paths := getTestPaths(t, turtle, 0.01)
+	checkTextPaths(t, paths, [][]Point{{{1, 1}, {1, 4}, {2, 4}}})
+}
+
+func TestTransformArcs(t *testing.T) {
+	// Uniform scaling keeps arcs circular, so they're drawn as arcs.
+	turtle := NewTurtle()
+	turtle.Scale(2, 2)
+	turtle.MoveArc(1, 90)
+	calls := getCanvasCalls(t, turtle)
+	if (len(calls) == 0) || (calls[0] != "arc 0 0 0 2 90 <nil>") {
+		t.Errorf("Expected a circular arc with radius 2, got %v", calls)
+	}
+
+	// Otherwise, the arc becomes part of an ellipse. The half circle with
+	// its center at (0, 1) in the turtle's frame becomes half of an ellipse
+	// with its center at (0, s) on the canvas.
+	for _, s := range []float64{2, -1} {
+		turtle = NewTurtle()
+		turtle.Scale(2, s)
+		turtle.MoveArc(1, 180)
+		paths := getTestPaths(t, turtle, 0.001)
+		if len(paths) != 1 {
+			t.Errorf("Expected 1 path for the arc scaled by %f, got %d", s,
+				len(paths))
+			continue
+		}
+		points := paths[0].Points
+		if len(points) < 8 {
+			t.Errorf("Expected many points for the arc scaled by %f, got %v",
+				s, points)
+		}
+		for i, p := range points {
+			d := math.Hypot(p.X/2, (p.Y-s)/s)
+			if (p.X < -1e-9) || (math.Abs(d-1) > 0.001) {
+				t.Errorf("Point %d of the arc scaled by %f, %v, isn't on the "+
+					"ellipse", i, s, p)
+			}
+		}
+		end := points[len(points)-1]
+		if (math.Abs(end.X) > 1e-9) || (math.Abs(end.Y-2*s) > 1e-9) {
+			t.Errorf("Expected the arc scaled by %f to end at (0, %f), got %v",
+				s, 2*s, end)
+		}
+		// The turtle's position and heading are in its frame, so they don't
+		// depend on the transformation.
+		_, state := getFinalState(t, turtle)
+		if (math.Abs(state.x) > 1e-9) || (math.Abs(state.y-2) > 1e-9) ||
+			(headingDifference(state.heading, 180) > 1e-9) {
+			t.Errorf("Expected the arc scaled by %f to end at (0, 2) in the "+
+				"frame with heading 180, got (%f, %f) with heading %f", s,
+				state.x, state.y, state.heading)
+		}
+	}
+}
+
+func TestTransformDots(t *testing.T) {
+	// Dots are moved by the transformation, and remain circular, with their
+	// diameter scaled by the square root of the factor areas are scaled by.
+	turtle := NewTurtle()
+	turtle.Scale(2, 8)
+	turtle.PenUp()
+	turtle.MoveForward(1)
+	turtle.Dot(1)
+	c, _ := getFinalState(t, turtle)
+	minX, minY, maxX, maxY := c.GetExtents()
+	if (minX > 0) || (minX < -0.01) || (maxX < 4) || (maxX > 4.01) {
+		t.Errorf("Expected the dot to span x from 0 to 4, got %f to %f",
+			minX, maxX)
+	}
+	if (minY > -2) || (minY < -2.01) || (maxY < 2) || (maxY > 2.01) {
+		t.Errorf("Expected the dot to span y from -2 to 2, got %f to %f",
+			minY, maxY)
+	}
+
+	// Canvases without dots draw a circle instead, which must also remain
+	// circular.
+	paths := getTestPaths(t, turtle, 0.001)
+	if len(paths) != 1 {
+		t.Fatalf("Expected 1 path for the dot, got %d", len(paths))
+	}
+	for i, p := range paths[0].Points {
+		if math.Abs(math.Hypot(p.X-2, p.Y)-2) > 0.001 {
+			t.Errorf("Point %d of the dot's outline, %v, isn't on the circle",
+				i, p)
+		}
+	}
+}
+
+func TestTransformExtents(t *testing.T) {
+	// A unit square drawn in a sheared frame becomes a parallelogram.
+	turtle := NewTurtle()
+	turtle.Transform(AffineTransform{XX: 1, XY: 1, YY: 1})
+	for i := 0; i < 4; i++ {
+		turtle.MoveForward(1)
+		turtle.Turn(90)
+	}
+	c, _ := getFinalState(t, turtle)
+	minX, minY, maxX, maxY := c.GetExtents()
+	if (minX > 0) || (minX < -0.01) || (maxX < 2) || (maxX > 2.01) {
+		t.Errorf("Expected the sheared square to span x from 0 to 2, got %f "+
+			"to %f", minX, maxX)
+	}
+	if (minY > 0) || (minY < -0.01) || (maxY < 1) || (maxY > 1.01) {
+		t.Errorf("Expected the sheared square to span y from 0 to 1, got %f "+
+			"to %f", minY, maxY)
+	}
+
+	// Line widths are scaled along with the frame, widening the extents.
+	turtle = NewTurtle()
+	turtle.SetLineWidth(1)
+	turtle.Scale(4, 4)
+	turtle.MoveForward(1)
+	c, _ = getFinalState(t, turtle)
+	minX, minY, maxX, maxY = c.GetExtents()
+	if (minX > -2) || (minX < -2.01) || (maxX < 6) || (maxX > 6.01) {
+		t.Errorf("Expected the scaled line to span x from -2 to 6, got %f "+
+			"to %f", minX, maxX)
+	}
+	if (minY > -2) || (minY < -2.01) || (maxY < 2) || (maxY > 2.01) {
+		t.Errorf("Expected the scaled line to span y from -2 to 2, got %f "+
+			"to %f", minY, maxY)
+	}
+
+	// Moves with the pen up don't affect the extents, even when transformed.
+	turtle = NewTurtle()
+	turtle.MoveForward(1)
+	turtle.Scale(10, 10)
+	turtle.PenUp()
+	turtle.MoveForward(1)
+	c, _ = getFinalState(t, turtle)
+	_, _, maxX, _ = c.GetExtents()
+	if maxX > 1.01 {
+		t.Errorf("The transformed move with the pen up changed the extents")
+	}
+}
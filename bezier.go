@@ -0,0 +1,199 @@
+package turtle_graphics
+
+// This file contains instructions for drawing Bezier curves, and the
+// functions for approximating them using line segments.
+
+import (
+	"fmt"
+	"math"
+)
+
+// An optional interface for canvases that are able to draw cubic Bezier
+// curves directly. Curves drawn to canvases that don't implement it are
+// approximated using DrawLine.
+type BezierCanvas interface {
+	Canvas
+	// Draws a cubic Bezier curve starting at p0 and ending at p3, with
+	// control points p1 and p2.
+	DrawBezier(p0, p1, p2, p3 Point) error
+}
+
+// The maximum number of times a curve is subdivided when approximating it
+// using line segments.
+const maxBezierSubdivisions = 16
+
+// Returns the point halfway between a and b.
+func midpoint(a, b Point) Point {
+	return Point{(a.X + b.X) / 2, (a.Y + b.Y) / 2}
+}
+
+// Returns the distance from p to the line through a and b, or to a if a and b
+// are the same.
+func distanceToLine(p, a, b Point) float64 {
+	dx := b.X - a.X
+	dy := b.Y - a.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+	return math.Abs(dx*(p.Y-a.Y)-dy*(p.X-a.X)) / length
+}
+
+// Appends points along a cubic Bezier curve to dst, such that the curve is
+// within tolerance of the line segments connecting them. The points exclude
+// p0, but always end with exactly p3. Uses recursive subdivision.
+func flattenCubic(dst []Point, p0, p1, p2, p3 Point, tolerance float64,
+	depth int) []Point {
+	flat := (distanceToLine(p1, p0, p3) <= tolerance) &&
+		(distanceToLine(p2, p0, p3) <= tolerance)
+	if flat || (depth >= maxBezierSubdivisions) {
+		return append(dst, p3)
+	}
+	// Split the curve in half using de Casteljau's algorithm.
+	p01 := midpoint(p0, p1)
+	p12 := midpoint(p1, p2)
+	p23 := midpoint(p2, p3)
+	p012 := midpoint(p01, p12)
+	p123 := midpoint(p12, p23)
+	middle := midpoint(p012, p123)
+	dst = flattenCubic(dst, p0, p01, p012, middle, tolerance, depth+1)
+	return flattenCubic(dst, middle, p123, p23, p3, tolerance, depth+1)
+}
+
+// Returns a default tolerance for approximating a curve with line segments:
+// a thousandth of the length of its control polygon.
+func defaultBezierTolerance(p0, p1, p2, p3 Point) float64 {
+	length := math.Hypot(p1.X-p0.X, p1.Y-p0.Y) +
+		math.Hypot(p2.X-p1.X, p2.Y-p1.Y) +
+		math.Hypot(p3.X-p2.X, p3.Y-p2.Y)
+	return length * 0.001
+}
+
+// Draws a cubic Bezier curve to the canvas, using DrawBezier if the canvas
+// implements BezierCanvas, or DrawLine otherwise.
+func drawBezier(c Canvas, p0, p1, p2, p3 Point) error {
+	bezierCanvas, ok := c.(BezierCanvas)
+	if ok {
+		return bezierCanvas.DrawBezier(p0, p1, p2, p3)
+	}
+	tolerance := defaultBezierTolerance(p0, p1, p2, p3)
+	points := flattenCubic(nil, p0, p1, p2, p3, tolerance, 0)
+	start := p0
+	for _, p := range points {
+		dx := p.X - start.X
+		dy := p.Y - start.Y
+		angle := math.Atan2(dy, dx) * 180.0 / math.Pi
+		e := c.DrawLine(start.X, start.Y, angle, math.Hypot(dx, dy))
+		if e != nil {
+			return e
+		}
+		start = p
+	}
+	return nil
+}
+
+// An instruction telling the turtle to move along a quadratic or cubic Bezier
+// curve. The control points and end point are given relative to the turtle's
+// position and heading at the start of the curve.
+type bezierInstruction struct {
+	// True for a cubic curve, false for a quadratic curve.
+	cubic bool
+	// Pairs of (forward, left) offsets: the control point(s), followed by the
+	// end point. Quadratic curves only use the first four entries.
+	offsets [6]float64
+}
+
+func (n *bezierInstruction) String() string {
+	if n.cubic {
+		return fmt.Sprintf("Move along cubic curve through (%f, %f), "+
+			"(%f, %f) to (%f, %f)", n.offsets[0], n.offsets[1], n.offsets[2],
+			n.offsets[3], n.offsets[4], n.offsets[5])
+	}
+	return fmt.Sprintf("Move along quadratic curve through (%f, %f) to "+
+		"(%f, %f)", n.offsets[0], n.offsets[1], n.offsets[2], n.offsets[3])
+}
+
+// Returns the curve's points in canvas coordinates, converting a quadratic
+// curve to the equivalent cubic curve. The offsets are multiplied by the
+// turtle's step scale.
+func (n *bezierInstruction) controlPoints(s *TurtleState) (p0, p1, p2,
+	p3 Point) {
+	x, y, angle := s.getPosition()
+	radians := angle * math.Pi / 180.0
+	cos := math.Cos(radians) * s.position.stepScale
+	sin := math.Sin(radians) * s.position.stepScale
+	toCanvas := func(forward, left float64) Point {
+		return Point{
+			X: x + forward*cos - left*sin,
+			Y: y + forward*sin + left*cos,
+		}
+	}
+	p0 = Point{x, y}
+	if n.cubic {
+		p1 = toCanvas(n.offsets[0], n.offsets[1])
+		p2 = toCanvas(n.offsets[2], n.offsets[3])
+		p3 = toCanvas(n.offsets[4], n.offsets[5])
+		return
+	}
+	control := toCanvas(n.offsets[0], n.offsets[1])
+	p3 = toCanvas(n.offsets[2], n.offsets[3])
+	p1 = Point{
+		X: p0.X + 2.0/3.0*(control.X-p0.X),
+		Y: p0.Y + 2.0/3.0*(control.Y-p0.Y),
+	}
+	p2 = Point{
+		X: p3.X + 2.0/3.0*(control.X-p3.X),
+		Y: p3.Y + 2.0/3.0*(control.Y-p3.Y),
+	}
+	return
+}
+
+func (n *bezierInstruction) Apply(s *TurtleState, c Canvas) error {
+	p0, p1, p2, p3 := n.controlPoints(s)
+	if !s.position.penUp {
+		e := drawBezier(c, p0, p1, p2, p3)
+		if e != nil {
+			return fmt.Errorf("Failed drawing curve: %w", e)
+		}
+	}
+	if s.fill != nil {
+		tolerance := defaultBezierTolerance(p0, p1, p2, p3)
+		for _, p := range flattenCubic(nil, p0, p1, p2, p3, tolerance, 0) {
+			s.fill.addPoint(s.framePoint(p.X, p.Y))
+		}
+	}
+	// The turtle ends up facing along the curve's tangent at its end point,
+	// which points from the last control point that differs from the end.
+	for _, p := range []Point{p2, p1, p0} {
+		if p != p3 {
+			s.position.angle = normalizeDegrees(math.Atan2(p3.Y-p.Y,
+				p3.X-p.X) * 180.0 / math.Pi)
+			break
+		}
+	}
+	s.moveTo(p3.X, p3.Y)
+	return nil
+}
+
+// Adds an instruction to move the turtle along a quadratic Bezier curve. The
+// control point and end point are given as offsets from the turtle's position
+// at the start of the curve: a distance in the direction the turtle is
+// facing, followed by a distance to the turtle's left, or to its right if the
+// turtle uses LogoOrientation, like the center of an arc drawn by MoveArc. The
+// turtle ends up at the end point, facing along the curve.
+func (t *Turtle) QuadraticBezier(controlForward, controlLeft, endForward,
+	endLeft float64) {
+	t.addOp(opQuadraticBezier, nil, controlForward,
+		t.leftDistance(controlLeft), endForward, t.leftDistance(endLeft))
+}
+
+// Adds an instruction to move the turtle along a cubic Bezier curve. The two
+// control points and the end point are given as offsets from the turtle's
+// position at the start of the curve, in the same way as for
+// QuadraticBezier.
+func (t *Turtle) CubicBezier(control1Forward, control1Left, control2Forward,
+	control2Left, endForward, endLeft float64) {
+	t.addOp(opCubicBezier, nil, control1Forward,
+		t.leftDistance(control1Left), control2Forward,
+		t.leftDistance(control2Left), endForward, t.leftDistance(endLeft))
+}
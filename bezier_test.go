@@ -0,0 +1,134 @@
+package turtle_graphics
+
+import (
+	"math"
+	"testing"
+)
+
+// An instruction that saves the turtle's position and heading.
+type saveStateTestInstruction struct {
+	x, y, heading float64
+}
+
+func (n *saveStateTestInstruction) Apply(s *TurtleState, c Canvas) error {
+	n.x, n.y = s.Position()
+	n.heading = s.Heading()
+	return nil
+}
+
+func (n *saveStateTestInstruction) String() string {
+	return "Save state"
+}
+
+// Renders the turtle to a DummyCanvas, and returns the canvas along with the
+// turtle's final position and heading. Adds an instruction to the turtle.
+func getFinalState(t *testing.T, turtle *Turtle) (*DummyCanvas,
+	*saveStateTestInstruction) {
+	state := &saveStateTestInstruction{}
+	turtle.Add(state)
+	c := NewDummyCanvas()
+	e := turtle.RenderToCanvas(c)
+	if e != nil {
+		t.Fatalf("Failed rendering turtle: %s", e)
+	}
+	return c, state
+}
+
+// Returns the point on the cubic curve at the given parameter, from 0 to 1.
+func cubicPoint(p0, p1, p2, p3 Point, at float64) Point {
+	u := 1 - at
+	a, b, c, d := u*u*u, 3*u*u*at, 3*u*at*at, at*at*at
+	return Point{
+		X: a*p0.X + b*p1.X + c*p2.X + d*p3.X,
+		Y: a*p0.Y + b*p1.Y + c*p2.Y + d*p3.Y,
+	}
+}
+
+// Returns the difference between two headings in degrees, from 0 to 180.
+func headingDifference(a, b float64) float64 {
+	d := math.Abs(normalizeDegrees(a - b))
+	if d > 180 {
+		d = 360 - d
+	}
+	return d
+}
+
+func TestBezierEndPosition(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t *Turtle)
+		// The control points and end point as offsets from (1, 2), in the
+		// direction of a turtle facing 90 degrees. Only the control points
+		// of cubic curves are used.
+		p1, p2, p3 Point
+	}{
+		{"Cubic", func(t *Turtle) {
+			t.CubicBezier(1, 1, 2, -1, 3, 0)
+		}, Point{-1, 1}, Point{1, 2}, Point{0, 3}},
+		{"Quadratic", func(t *Turtle) {
+			t.QuadraticBezier(1.5, 1.5, 3, 0)
+		}, Point{-1, 1}, Point{-1, 2}, Point{0, 3}},
+		{"Final control point at the end", func(t *Turtle) {
+			t.CubicBezier(1, 1, 3, 0, 3, 0)
+		}, Point{-1, 1}, Point{0, 3}, Point{0, 3}},
+		{"Scaled", func(t *Turtle) {
+			t.ScaleStep(2)
+			t.CubicBezier(0.5, 0.5, 1, -0.5, 1.5, 0)
+		}, Point{-1, 1}, Point{1, 2}, Point{0, 3}},
+	}
+	start := Point{1, 2}
+	for _, test := range tests {
+		turtle := NewTurtleWithOptions(TurtleOptions{X: 1, Y: 2, Heading: 90})
+		test.build(turtle)
+		_, state := getFinalState(t, turtle)
+		end := Point{start.X + test.p3.X, start.Y + test.p3.Y}
+		if (math.Abs(state.x-end.X) > 1e-9) ||
+			(math.Abs(state.y-end.Y) > 1e-9) {
+			t.Errorf("%s: expected to end at %v, got (%f, %f)", test.name,
+				end, state.x, state.y)
+		}
+		// The heading must be tangent to the curve at its end, so it points
+		// from a point just before the end of the curve to the end.
+		p1 := Point{start.X + test.p1.X, start.Y + test.p1.Y}
+		p2 := Point{start.X + test.p2.X, start.Y + test.p2.Y}
+		nearEnd := cubicPoint(start, p1, p2, end, 0.9999)
+		expected := math.Atan2(end.Y-nearEnd.Y, end.X-nearEnd.X) * 180 /
+			math.Pi
+		if headingDifference(state.heading, expected) > 0.01 {
+			t.Errorf("%s: expected to end with heading %f, got %f",
+				test.name, normalizeDegrees(expected), state.heading)
+		}
+	}
+}
+
+func TestBezierExtents(t *testing.T) {
+	// The curve's control points are at a height of 1, but the curve itself
+	// only reaches a height of 0.75.
+	turtle := NewTurtle()
+	turtle.CubicBezier(0, 1, 1, 1, 1, 0)
+	c, _ := getFinalState(t, turtle)
+	minX, minY, maxX, maxY := c.GetExtents()
+	t.Logf("Curve extents: (%f, %f) to (%f, %f)", minX, minY, maxX, maxY)
+	if (minX > 0) || (minX < -0.01) || (maxX < 1) || (maxX > 1.01) {
+		t.Errorf("Expected the curve to span x from 0 to 1, got %f to %f",
+			minX, maxX)
+	}
+	if (minY > 0) || (minY < -0.01) || (maxY < 0.75) || (maxY > 0.76) {
+		t.Errorf("Expected the curve to span y from 0 to 0.75, got %f to %f",
+			minY, maxY)
+	}
+
+	// Nothing is drawn with the pen up.
+	turtle = NewTurtle()
+	turtle.MoveForward(1)
+	turtle.PenUp()
+	turtle.CubicBezier(0, 5, 1, 5, 1, 0)
+	c, state := getFinalState(t, turtle)
+	_, _, _, maxY = c.GetExtents()
+	if maxY != 0 {
+		t.Errorf("The curve drawn with the pen up changed the extents")
+	}
+	if math.Abs(state.x-2) > 1e-9 {
+		t.Errorf("Expected to end at x = 2, got %f", state.x)
+	}
+}
@@ -0,0 +1,152 @@
+package turtle_graphics
+
+// This file contains the cameras used to project a 3D turtle's drawing onto a
+// 2D canvas.
+
+import (
+	"fmt"
+	"math"
+)
+
+// Projects 3D line segments onto a 2D canvas.
+type Camera interface {
+	// Returns the canvas coordinates of the endpoints of the line segment from
+	// a to b. If only part of the segment is visible, the endpoints of the
+	// visible part are returned. Returns false if none of the segment is
+	// visible.
+	ProjectSegment(a, b Vector3) (Point, Point, bool)
+}
+
+// The position and orientation of a camera. The right, up and forward vectors
+// are orthogonal unit vectors.
+type cameraFrame struct {
+	eye                Vector3
+	right, up, forward Vector3
+}
+
+// Returns the frame of a camera at eye looking towards target. The camera is
+// rotated so that the given up direction appears upwards on the canvas.
+func newCameraFrame(eye, target, up Vector3) (cameraFrame, error) {
+	forward := target.Sub(eye).Normalize()
+	if forward == (Vector3{}) {
+		return cameraFrame{}, fmt.Errorf("The camera's target must differ " +
+			"from its position")
+	}
+	right := forward.Cross(up).Normalize()
+	if right == (Vector3{}) {
+		return cameraFrame{}, fmt.Errorf("The camera's up direction must " +
+			"not be parallel to the direction it faces")
+	}
+	return cameraFrame{
+		eye:     eye,
+		right:   right,
+		up:      right.Cross(forward),
+		forward: forward,
+	}, nil
+}
+
+// Returns p's coordinates relative to the camera: its distance to the right,
+// distance up, and depth in front of the camera.
+func (f *cameraFrame) toView(p Vector3) Vector3 {
+	d := p.Sub(f.eye)
+	return Vector3{d.Dot(f.right), d.Dot(f.up), d.Dot(f.forward)}
+}
+
+// A camera using an orthographic projection, where lines that are parallel in
+// 3D remain parallel on the canvas, and distant objects aren't smaller. The
+// canvas origin is the point the camera faces, and canvas units are the same
+// as 3D units.
+type OrthographicCamera struct {
+	frame cameraFrame
+}
+
+// Returns an orthographic camera at eye, looking towards target, rotated so
+// that the given up direction appears upwards on the canvas. Returns an error
+// if eye and target are the same, or up is parallel to the direction the
+// camera faces.
+func NewOrthographicCamera(eye, target, up Vector3) (*OrthographicCamera,
+	error) {
+	frame, e := newCameraFrame(eye, target, up)
+	if e != nil {
+		return nil, e
+	}
+	return &OrthographicCamera{
+		frame: frame,
+	}, nil
+}
+
+func (c *OrthographicCamera) ProjectSegment(a, b Vector3) (Point, Point,
+	bool) {
+	viewA := c.frame.toView(a)
+	viewB := c.frame.toView(b)
+	return Point{viewA.X, viewA.Y}, Point{viewB.X, viewB.Y}, true
+}
+
+// The default distance to the near clipping plane of a PerspectiveCamera.
+const DefaultNearPlane = 1e-3
+
+// A camera using a perspective projection, where distant objects appear
+// smaller. The point the camera faces is at the canvas origin, and the edges
+// of the field of view are at -1 and 1 on the canvas.
+type PerspectiveCamera struct {
+	frame cameraFrame
+	// The canvas distance per unit of (distance from the center of view /
+	// depth).
+	focalLength float64
+	// Segments closer to the camera than this depth are clipped.
+	near float64
+}
+
+// Returns a perspective camera at eye, looking towards target, rotated so that
+// the given up direction appears upwards on the canvas. The field of view is
+// the angle, in degrees, between opposite edges of the view, and must be
+// between 0 and 180. Lines behind the camera, or closer than
+// DefaultNearPlane, are clipped. Returns an error if the arguments are
+// invalid.
+func NewPerspectiveCamera(eye, target, up Vector3,
+	fieldOfView float64) (*PerspectiveCamera, error) {
+	if !((fieldOfView > 0) && (fieldOfView < 180)) {
+		return nil, fmt.Errorf("Invalid field of view: %f degrees",
+			fieldOfView)
+	}
+	frame, e := newCameraFrame(eye, target, up)
+	if e != nil {
+		return nil, e
+	}
+	halfAngle := fieldOfView * math.Pi / 360.0
+	return &PerspectiveCamera{
+		frame:       frame,
+		focalLength: 1 / math.Tan(halfAngle),
+		near:        DefaultNearPlane,
+	}, nil
+}
+
+// Returns the canvas position of a point in view coordinates, which must be in
+// front of the camera.
+func (c *PerspectiveCamera) project(v Vector3) Point {
+	return Point{
+		X: v.X * c.focalLength / v.Z,
+		Y: v.Y * c.focalLength / v.Z,
+	}
+}
+
+func (c *PerspectiveCamera) ProjectSegment(a, b Vector3) (Point, Point,
+	bool) {
+	viewA := c.frame.toView(a)
+	viewB := c.frame.toView(b)
+	// Clip the segment against the near plane, so we never divide by a depth
+	// that is zero or negative.
+	if (viewA.Z < c.near) && (viewB.Z < c.near) {
+		return Point{}, Point{}, false
+	}
+	if viewA.Z < c.near {
+		t := (c.near - viewA.Z) / (viewB.Z - viewA.Z)
+		viewA = viewA.Add(viewB.Sub(viewA).Scale(t))
+		viewA.Z = c.near
+	} else if viewB.Z < c.near {
+		t := (c.near - viewB.Z) / (viewA.Z - viewB.Z)
+		viewB = viewB.Add(viewA.Sub(viewB).Scale(t))
+		viewB.Z = c.near
+	}
+	return c.project(viewA), c.project(viewB), true
+}
@@ -0,0 +1,120 @@
+package turtle_graphics
+
+// This file contains functions for building a turtle's instructions from
+// other turtles, either by copying their instructions or by adding
+// instructions that carry them out without copying them.
+
+import (
+	"fmt"
+)
+
+// Carries out another turtle's instructions, as if they were drawn on a canvas
+// whose origin is at this turtle's position, whose heading of 0 points in the
+// direction this turtle is facing, and whose units are scaled by the given
+// factor. The other turtle uses a separate state, starting at its own starting
+// position with a step scale of 1 and an empty position stack, but with this
+// turtle's style and line width, the given pen state and the given procedure,
+// used by Recurse. Afterwards, this turtle's style is passed to the canvas
+// again if the other turtle changed it. Returns the other turtle's final state,
+// along with a transformation that converts its coordinates to this turtle's
+// coordinates.
+func runIsolated(sub *Turtle, s *TurtleState, c Canvas, scale float64,
+	penUp bool, level recursionLevel) (*TurtleState, transformCanvas, error) {
+	if sub == nil {
+		return nil, transformCanvas{}, ErrNilSubprogram
+	}
+	x, y, angle := s.getPosition()
+	subCanvas := newTransformCanvas(c, x, y, angle, scale)
+	// The canvas may already be transformed, in which case the sub turtle's
+	// canvas combines both transformations. The frame only contains this
+	// turtle's transformation, and isn't used for drawing.
+	frame := newTransform(x, y, angle, scale)
+	subState := &TurtleState{
+		position:        initialPosition(sub.start),
+		random:          s.random,
+		subprogramDepth: s.subprogramDepth,
+		start:           sub.start,
+		styleCount:      s.styleCount,
+		recursion:       level,
+	}
+	subState.position.penUp = penUp
+	subState.position.style = s.position.style
+	subState.position.styleID = s.position.styleID
+	// The canvas scales line widths, so the width must be scaled by the
+	// inverse to look the same.
+	if frame.scale != 0 {
+		subState.position.lineWidth = s.position.lineWidth / frame.scale
+	}
+	lineWidth := subState.position.lineWidth
+	e := runSubprogram(&(sub.instructions), subState, subCanvas)
+	if e != nil {
+		return nil, transformCanvas{}, e
+	}
+	if (subState.position.styleID != s.position.styleID) ||
+		(subState.position.lineWidth != lineWidth) {
+		e = c.SetStyle(s.canvasStyle())
+	}
+	return subState, frame, e
+}
+
+// An instruction that carries out another turtle's instructions relative to
+// the turtle's position and heading, and then moves the turtle to where the
+// other turtle ended up.
+type callInstruction struct {
+	sub   *Turtle
+	scale float64
+}
+
+func (n *callInstruction) String() string {
+	return fmt.Sprintf("Call %d instructions, scaled by %f",
+		n.sub.Len(), n.scale)
+}
+
+func (n *callInstruction) Apply(s *TurtleState, c Canvas) error {
+	return s.callTurtle(c, n.sub, n.scale*s.position.stepScale, s.recursion)
+}
+
+// Carries out the sub turtle's instructions relative to the turtle's position
+// and heading, scaled by the given factor and using the given procedure, and
+// then moves the turtle to where the sub turtle ended up.
+func (s *TurtleState) callTurtle(c Canvas, sub *Turtle, scale float64,
+	level recursionLevel) error {
+	subState, frame, e := runIsolated(sub, s, c, scale, s.position.penUp,
+		level)
+	if e != nil {
+		return e
+	}
+	x, y, angle := subState.getPosition()
+	x, y = frame.transformPoint(x, y)
+	s.moveTo(x, y)
+	s.position.angle = normalizeDegrees(frame.transformAngle(angle))
+	return nil
+}
+
+// Adds copies of all of the other turtle's instructions to the end of this
+// turtle's instructions. Angles were already converted using the other
+// turtle's TurtleOptions when its instructions were added, so they are
+// unaffected by this turtle's options. The other turtle's seed and starting
+// position are ignored. Subprograms used by the other turtle's instructions,
+// such as shapes passed to Stamp, are shared rather than copied.
+func (t *Turtle) Append(other *Turtle) {
+	if t.stream == nil {
+		t.instructions.addList(&(other.instructions))
+		return
+	}
+	for cursor := other.instructions.cursor(); cursor.valid(); cursor.next() {
+		op, operands, object := cursor.get()
+		t.addOp(op, object, operands...)
+	}
+}
+
+// Adds an instruction to draw the sub turtle relative to this turtle's
+// position and heading, with its distances multiplied by scale, and then move
+// to where it ended up. The sub turtle isn't copied, and is ignored if nil.
+// Use CallRecursive for a turtle that calls itself.
+func (t *Turtle) Call(sub *Turtle, scale float64) {
+	if sub == nil {
+		return
+	}
+	t.addOp(opCall, sub, scale)
+}
@@ -0,0 +1,82 @@
+package turtle_graphics
+
+// This file contains instructions for marking the turtle's position: dots,
+// and stamps that draw a copy of a shape.
+
+import (
+	"fmt"
+)
+
+// An optional interface for canvases that are able to draw filled circles.
+// Dots drawn to canvases that don't implement it are drawn as the outline of
+// a circle using DrawArc.
+type DotCanvas interface {
+	Canvas
+	// Draws a filled circle centered on (x, y) with the given diameter, using
+	// the current style.
+	DrawDot(x, y, diameter float64) error
+}
+
+// Draws a dot to the canvas, using DrawDot if the canvas implements
+// DotCanvas, or DrawArc otherwise.
+func drawDot(c Canvas, x, y, diameter float64) error {
+	dotCanvas, ok := c.(DotCanvas)
+	if ok {
+		return dotCanvas.DrawDot(x, y, diameter)
+	}
+	radius := diameter / 2
+	return c.DrawArc(x, y-radius, 0, radius, 360)
+}
+
+// An instruction telling the turtle to draw a dot at its position.
+type dotInstruction struct {
+	diameter float64
+}
+
+func (n *dotInstruction) String() string {
+	return fmt.Sprintf("Draw a dot with diameter %f", n.diameter)
+}
+
+func (n *dotInstruction) Apply(s *TurtleState, c Canvas) error {
+	x, y := s.Position()
+	e := drawDot(c, x, y, n.diameter)
+	if e != nil {
+		return fmt.Errorf("Failed drawing dot: %w", e)
+	}
+	return nil
+}
+
+// An instruction telling the turtle to draw a shape at its position.
+type stampInstruction struct {
+	shape *Turtle
+}
+
+func (n *stampInstruction) String() string {
+	return fmt.Sprintf("Stamp a shape of %d instructions",
+		n.shape.Len())
+}
+
+func (n *stampInstruction) Apply(s *TurtleState, c Canvas) error {
+	// The shape is drawn by a separate turtle, so it can't affect this
+	// turtle's position, pen or position stack.
+	_, _, e := runIsolated(n.shape, s, c, 1, false, s.recursion)
+	return e
+}
+
+// Adds an instruction to draw a filled circle with the given diameter,
+// centered on the turtle's position, using the current style. The dot is
+// drawn even if the pen is up. Canvases that don't implement DotCanvas draw
+// the outline of the circle instead.
+func (t *Turtle) Dot(diameter float64) {
+	t.addOp(opDot, nil, diameter)
+}
+
+// Adds an instruction to draw the given shape at the turtle's position and
+// heading, even if the pen is up, without moving the turtle. The shape isn't
+// copied. Nothing is added if the shape is nil.
+func (t *Turtle) Stamp(shape *Turtle) {
+	if shape == nil {
+		return
+	}
+	t.addOp(opStamp, shape)
+}
@@ -0,0 +1,170 @@
+// This defines a command-line program that measures the memory used by a
+// turtle's instructions, and the time taken to record and replay them, for a
+// large dragon curve. It compares the turtle's built-in instructions against
+// equivalent user-defined instructions, each of which is a separately
+// allocated value behind an Instruction interface, as all instructions were
+// stored before the turtle switched to compact storage.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/yalue/turtle_graphics"
+	"math"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// A user-defined equivalent of Turtle.MoveForward.
+type boxedMoveForward struct {
+	distance float64
+}
+
+func (n *boxedMoveForward) Apply(s *turtle_graphics.TurtleState,
+	c turtle_graphics.Canvas) error {
+	x, y := s.Position()
+	radians := s.Heading() * math.Pi / 180.0
+	newX := x + n.distance*math.Cos(radians)
+	newY := y + n.distance*math.Sin(radians)
+	if s.PenDown() {
+		e := c.DrawLine(x, y, s.Heading(), n.distance)
+		if e != nil {
+			return e
+		}
+	}
+	s.SetPosition(newX, newY)
+	return nil
+}
+
+func (n *boxedMoveForward) String() string {
+	return fmt.Sprintf("boxed forward %f", n.distance)
+}
+
+// A user-defined equivalent of Turtle.Turn.
+type boxedTurn struct {
+	degrees float64
+}
+
+func (n *boxedTurn) Apply(s *turtle_graphics.TurtleState,
+	c turtle_graphics.Canvas) error {
+	s.SetHeading(s.Heading() + n.degrees)
+	return nil
+}
+
+func (n *boxedTurn) String() string {
+	return fmt.Sprintf("boxed turn %f", n.degrees)
+}
+
+// Records a dragon curve with the given number of iterations, using either
+// the built-in instructions or the boxed user-defined ones. The curve contains
+// 2^iterations line segments.
+func recordDragon(t *turtle_graphics.Turtle, iterations int, sign float64,
+	boxed bool) {
+	if iterations == 0 {
+		if boxed {
+			t.Add(&boxedMoveForward{distance: 1})
+		} else {
+			t.MoveForward(1)
+		}
+		return
+	}
+	recordDragon(t, iterations-1, 1, boxed)
+	if boxed {
+		t.Add(&boxedTurn{degrees: 90 * sign})
+	} else {
+		t.Turn(90 * sign)
+	}
+	recordDragon(t, iterations-1, -1, boxed)
+}
+
+// Returns a new turtle containing a dragon curve.
+func newDragon(iterations int, boxed bool) *turtle_graphics.Turtle {
+	t := turtle_graphics.NewTurtle()
+	recordDragon(t, iterations, 1, boxed)
+	return t
+}
+
+// Returns the number of heap bytes retained by a newly recorded dragon curve.
+func retainedBytes(iterations int, boxed bool) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	t := newDragon(iterations, boxed)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(t)
+	return after.HeapAlloc - before.HeapAlloc
+}
+
+// Records the dragon curve b.N times.
+func benchmarkRecording(b *testing.B, iterations int, boxed bool) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newDragon(iterations, boxed)
+	}
+}
+
+// Renders the turtle to a DummyCanvas b.N times, returning an error if any
+// rendering fails.
+func benchmarkReplay(b *testing.B, t *turtle_graphics.Turtle) error {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := t.RenderToCanvas(turtle_graphics.NewDummyCanvas())
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// Prints the measurements for a single kind of instruction storage.
+func runBenchmarks(name string, iterations int, boxed bool) error {
+	fmt.Printf("%s:\n", name)
+	retained := retainedBytes(iterations, boxed)
+	fmt.Printf("  Retained memory: %.1f MB\n", float64(retained)/1.0e6)
+	result := testing.Benchmark(func(b *testing.B) {
+		benchmarkRecording(b, iterations, boxed)
+	})
+	fmt.Printf("  Recording: %s %s\n", result, result.MemString())
+	t := newDragon(iterations, boxed)
+	var renderError error
+	result = testing.Benchmark(func(b *testing.B) {
+		renderError = benchmarkReplay(b, t)
+		if renderError != nil {
+			b.FailNow()
+		}
+	})
+	if renderError != nil {
+		return fmt.Errorf("Failed rendering %s: %w", name, renderError)
+	}
+	fmt.Printf("  Replay: %s %s\n", result, result.MemString())
+	return nil
+}
+
+func run() int {
+	var iterations int
+	flag.IntVar(&iterations, "iterations", 20, "The number of dragon curve "+
+		"iterations. The curve contains 2^iterations line segments.")
+	flag.Parse()
+	if (iterations < 0) || (iterations > 26) {
+		fmt.Printf("Invalid number of iterations: %d\n", iterations)
+		return 1
+	}
+	fmt.Printf("Dragon curve with %d iterations (%d instructions)\n",
+		iterations, newDragon(iterations, false).Len())
+	e := runBenchmarks("Built-in instructions", iterations, false)
+	if e == nil {
+		e = runBenchmarks("Boxed user-defined instructions", iterations, true)
+	}
+	if e != nil {
+		fmt.Printf("Error: %s\n", e)
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run())
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"github.com/yalue/turtle_graphics"
+	"testing"
+)
+
+// The number of iterations used by the Benchmark functions. Smaller than the
+// executable's default, so that running every benchmark doesn't take long.
+const testIterations = 16
+
+// Returns the PNG image of the dragon curve drawn using either the built-in or
+// the boxed instructions.
+func renderDragonPNG(t *testing.T, boxed bool) []byte {
+	var buffer bytes.Buffer
+	e := turtle_graphics.SaveTurtleAsPNG(newDragon(10, boxed), 400, &buffer)
+	if e != nil {
+		t.Fatalf("Failed rendering dragon curve: %s", e)
+	}
+	return buffer.Bytes()
+}
+
+// Checks that the boxed instructions draw the same curve as the built-in ones,
+// so the benchmarks compare equivalent work.
+func TestBoxedInstructions(t *testing.T) {
+	builtIn := renderDragonPNG(t, false)
+	boxed := renderDragonPNG(t, true)
+	if !bytes.Equal(builtIn, boxed) {
+		t.Errorf("The boxed instructions drew a different image")
+	}
+}
+
+func BenchmarkRecordBuiltIn(b *testing.B) {
+	benchmarkRecording(b, testIterations, false)
+}
+
+func BenchmarkRecordBoxed(b *testing.B) {
+	benchmarkRecording(b, testIterations, true)
+}
+
+func BenchmarkReplayBuiltIn(b *testing.B) {
+	e := benchmarkReplay(b, newDragon(testIterations, false))
+	if e != nil {
+		b.Fatalf("Failed rendering: %s", e)
+	}
+}
+
+func BenchmarkReplayBoxed(b *testing.B) {
+	e := benchmarkReplay(b, newDragon(testIterations, true))
+	if e != nil {
+		b.Fatalf("Failed rendering: %s", e)
+	}
+}
@@ -0,0 +1,144 @@
+package turtle_graphics
+
+// This file contains the instructions for filling the shapes traced by the
+// turtle.
+
+import (
+	"fmt"
+	"math"
+)
+
+// Determines which parts of a self-intersecting polygon are filled.
+type FillRule int
+
+const (
+	// Fills points that the polygon's outline winds around a nonzero number
+	// of times.
+	NonZeroFill FillRule = iota
+	// Fills points that are enclosed by an odd number of the polygon's edges.
+	EvenOddFill
+)
+
+func (r FillRule) String() string {
+	switch r {
+	case NonZeroFill:
+		return "nonzero"
+	case EvenOddFill:
+		return "evenodd"
+	}
+	return fmt.Sprintf("unknown fill rule %d", int(r))
+}
+
+// An optional interface for canvases that are able to fill polygons. Fills
+// are skipped when rendering to canvases that don't implement it, so lines
+// are still drawn but filled regions are not.
+type FillCanvas interface {
+	Canvas
+	// Fills the polygon with the given vertices, which is implicitly closed
+	// by an edge from the last point to the first. The style and rule are
+	// those passed to Turtle.BeginFill, and don't affect the canvas's stroke
+	// style.
+	FillPolygon(points []Point, style StrokeStyle, rule FillRule) error
+}
+
+// Records the outline of a shape while it is being filled.
+type fillState struct {
+	style StrokeStyle
+	rule  FillRule
+	// The vertices of the outline, on the canvas rather than in the turtle's
+	// frame.
+	points []Point
+}
+
+// Adds a vertex to the outline, unless it's the same as the previous vertex.
+func (f *fillState) addPoint(x, y float64) {
+	p := Point{x, y}
+	if (len(f.points) != 0) && (f.points[len(f.points)-1] == p) {
+		return
+	}
+	f.points = append(f.points, p)
+}
+
+// Adds the vertices of an arc to the outline, given the same arguments as
+// Canvas.DrawArc, and the turtle's frame, which may be nil. Excludes the final
+// point, which is added when the turtle's position is updated.
+func (f *fillState) addArc(frame *AffineTransform, x, y, angle, radius,
+	degrees float64) {
+	// Approximate the arc to within a thousandth of its radius.
+	tolerance := math.Abs(radius) * 0.001
+	points := flattenArc(x, y, angle, radius, degrees, tolerance)
+	for _, p := range points[0 : len(points)-1] {
+		if frame != nil {
+			p.X, p.Y = frame.Apply(p.X, p.Y)
+		}
+		f.addPoint(p.X, p.Y)
+	}
+}
+
+// Starts recording the outline of a shape to fill.
+type beginFillInstruction struct {
+	style StrokeStyle
+	rule  FillRule
+}
+
+func (n *beginFillInstruction) String() string {
+	return fmt.Sprintf("Begin fill (%s rule)", n.rule)
+}
+
+func (n *beginFillInstruction) Apply(s *TurtleState, c Canvas) error {
+	if s.fill != nil {
+		return ErrFillInProgress
+	}
+	s.fill = &fillState{
+		style:  n.style,
+		rule:   n.rule,
+		points: make([]Point, 0, 64),
+	}
+	s.fill.addPoint(s.framePoint(s.position.x, s.position.y))
+	return nil
+}
+
+// Fills the shape traced since the matching beginFillInstruction.
+type endFillInstruction struct{}
+
+func (n *endFillInstruction) String() string {
+	return "End fill"
+}
+
+func (n *endFillInstruction) Apply(s *TurtleState, c Canvas) error {
+	f := s.fill
+	if f == nil {
+		return ErrNoFill
+	}
+	s.fill = nil
+	// The outline may have returned to its starting point.
+	points := f.points
+	if (len(points) > 1) && (points[0] == points[len(points)-1]) {
+		points = points[0 : len(points)-1]
+	}
+	// The outline was recorded in the coordinates of the canvas rather than
+	// the turtle's frame, which may have changed during the fill.
+	filler, ok := s.baseCanvas(c).(FillCanvas)
+	if !ok || (len(points) < 3) {
+		return nil
+	}
+	return filler.FillPolygon(points, f.style, f.rule)
+}
+
+// Adds an instruction to start tracing the outline of a shape to fill. Every
+// position the turtle moves to, whether or not its pen is down, is added to
+// the outline until EndFill is called. Arcs are approximated by line
+// segments. Pushing and popping the turtle's position doesn't affect the
+// outline, other than adding the popped position to it. Fills may not be
+// nested.
+func (t *Turtle) BeginFill(style StrokeStyle, rule FillRule) {
+	t.addOp(opBeginFill, style, float64(rule))
+}
+
+// Adds an instruction to fill the shape traced since the previous BeginFill.
+// The shape is filled on the canvas when this instruction is carried out, so
+// the fill is drawn over any lines drawn since BeginFill. Canvases that don't
+// implement the FillCanvas interface ignore fills.
+func (t *Turtle) EndFill() {
+	t.addOp(opEndFill, nil)
+}
@@ -0,0 +1,185 @@
+package turtle_graphics
+
+import (
+	"errors"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// A canvas that records the polygons it's asked to fill.
+type fillRecordingCanvas struct {
+	recordingCanvas
+	polygons [][]Point
+	rules    []FillRule
+}
+
+func (c *fillRecordingCanvas) FillPolygon(points []Point, style StrokeStyle,
+	rule FillRule) error {
+	c.polygons = append(c.polygons, append([]Point(nil), points...))
+	c.rules = append(c.rules, rule)
+	return nil
+}
+
+// Returns a turtle filling a five-pointed star, drawn without lifting the
+// pen, using the given rule. The star's outline is drawn with the pen up, so
+// only the fill is visible. Also returns the star's vertices.
+func getStarTurtle(rule FillRule) (*Turtle, []Point) {
+	t := NewTurtle()
+	t.PenUp()
+	t.BeginFill(GetColorStyle(color.Black), rule)
+	vertices := make([]Point, 5)
+	x, y, heading := 0.0, 0.0, 0.0
+	for i := range vertices {
+		vertices[i] = Point{x, y}
+		t.MoveForward(2)
+		x, y = moveDegrees(x, y, heading, 2)
+		t.Turn(144)
+		heading += 144
+	}
+	t.EndFill()
+	return t, vertices
+}
+
+// Returns true if the pixel containing the point on the canvas isn't white.
+func isFilled(c *RGBACanvas, p Point) bool {
+	x, y := c.PointToPixel(p.X, p.Y)
+	r, g, b, _ := c.At(x, y).RGBA()
+	return (r != 0xffff) || (g != 0xffff) || (b != 0xffff)
+}
+
+func TestFillRules(t *testing.T) {
+	for _, rule := range []FillRule{EvenOddFill, NonZeroFill} {
+		turtle, vertices := getStarTurtle(rule)
+		c, e := NewRGBACanvas(200, 200, -1, -2, 3, 2, color.White)
+		if e != nil {
+			t.Fatalf("Failed creating canvas: %s", e)
+		}
+		e = turtle.RenderToCanvas(c)
+		if e != nil {
+			t.Fatalf("Failed rendering star: %s", e)
+		}
+		center := Point{}
+		for _, v := range vertices {
+			center.X += v.X / 5
+			center.Y += v.Y / 5
+		}
+		// The middle of the star is enclosed twice, so only the nonzero
+		// rule fills it.
+		if isFilled(c, center) != (rule == NonZeroFill) {
+			t.Errorf("The %s rule filled the star's center incorrectly", rule)
+		}
+		// Each point of the star is enclosed once, so both rules fill it.
+		for i, v := range vertices {
+			p := Point{(v.X + center.X) / 2, (v.Y + center.Y) / 2}
+			if !isFilled(c, p) {
+				t.Errorf("The %s rule didn't fill point %d of the star", rule,
+					i)
+			}
+		}
+		if isFilled(c, Point{-0.9, 1.9}) {
+			t.Errorf("The %s rule filled outside of the star", rule)
+		}
+	}
+}
+
+func TestFillArcs(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.BeginFill(GetColorStyle(color.Black), NonZeroFill)
+	turtle.MoveArc(1, 360)
+	turtle.EndFill()
+	c := &fillRecordingCanvas{}
+	e := turtle.RenderToCanvas(c)
+	if e != nil {
+		t.Fatalf("Failed rendering circle: %s", e)
+	}
+	if len(c.polygons) != 1 {
+		t.Fatalf("Expected 1 polygon to be filled, got %d", len(c.polygons))
+	}
+	points := c.polygons[0]
+	if len(points) < 32 {
+		t.Errorf("Expected the circle to use many points, got %d",
+			len(points))
+	}
+	for i, p := range points {
+		if math.Abs(math.Hypot(p.X, p.Y-1)-1) > 1e-9 {
+			t.Errorf("Point %d of the outline, %v, isn't on the circle", i, p)
+		}
+	}
+}
+
+func TestFillPushPop(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.BeginFill(GetColorStyle(color.Black), EvenOddFill)
+	turtle.MoveForward(1)
+	turtle.PushPosition()
+	turtle.MoveForward(5)
+	turtle.PopPosition()
+	turtle.Turn(90)
+	turtle.MoveForward(1)
+	turtle.EndFill()
+	c := &fillRecordingCanvas{}
+	e := turtle.RenderToCanvas(c)
+	if e != nil {
+		t.Fatalf("Failed rendering fill: %s", e)
+	}
+	if len(c.polygons) != 1 {
+		t.Fatalf("Expected 1 polygon to be filled, got %d", len(c.polygons))
+	}
+	if c.rules[0] != EvenOddFill {
+		t.Errorf("Expected the %s rule, got %s", EvenOddFill, c.rules[0])
+	}
+	// The popped position is added to the outline again.
+	checkPathPoints(t, Path{Points: c.polygons[0]}, []Point{{0, 0}, {1, 0},
+		{6, 0}, {1, 0}, {1, 1}})
+}
+
+func TestFillErrors(t *testing.T) {
+	style := GetColorStyle(color.Black)
+	turtle := NewTurtle()
+	turtle.MoveForward(1)
+	turtle.EndFill()
+	checkValidateError(t, turtle, 1, ErrNoFill)
+
+	turtle = NewTurtle()
+	turtle.BeginFill(style, NonZeroFill)
+	turtle.MoveForward(1)
+	turtle.BeginFill(style, NonZeroFill)
+	turtle.EndFill()
+	checkValidateError(t, turtle, 2, ErrFillInProgress)
+
+	turtle = NewTurtle()
+	turtle.MoveForward(1)
+	turtle.BeginFill(style, NonZeroFill)
+	turtle.MoveForward(1)
+	checkValidateError(t, turtle, 1, ErrUnclosedFill)
+
+	// Subprograms may not end fills they didn't begin.
+	sub := NewTurtle()
+	sub.EndFill()
+	turtle = NewTurtle()
+	turtle.BeginFill(style, NonZeroFill)
+	turtle.Stamp(sub)
+	turtle.EndFill()
+	e := turtle.Validate()
+	if e == nil {
+		t.Errorf("Didn't get an error ending a fill in a subprogram")
+	} else {
+		t.Logf("Got expected error: %s", e)
+	}
+
+	// The same errors are returned while rendering.
+	turtle = NewTurtle()
+	turtle.BeginFill(style, NonZeroFill)
+	turtle.BeginFill(style, NonZeroFill)
+	e = turtle.RenderToCanvas(NewDummyCanvas())
+	if !errors.Is(e, ErrFillInProgress) {
+		t.Errorf("Expected %q rendering, got %q", ErrFillInProgress, e)
+	}
+	turtle = NewTurtle()
+	turtle.EndFill()
+	e = turtle.RenderToCanvas(NewDummyCanvas())
+	if !errors.Is(e, ErrNoFill) {
+		t.Errorf("Expected %q rendering, got %q", ErrNoFill, e)
+	}
+}
@@ -0,0 +1,207 @@
+package turtle_graphics
+
+// This file contains a simple single-stroke vector font, and the instruction
+// for writing text with it.
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// The glyphs are drawn on a grid where x ranges from 0 to 4 and y ranges from
+// 0 to 9, with y increasing upwards.
+const (
+	// The grid row on which characters sit.
+	fontBaseline = 2
+	// The height of capital letters and digits above the baseline, in grid
+	// units.
+	fontCapHeight = 6
+	// The distance from the start of one character to the start of the next,
+	// in grid units.
+	fontAdvance = 6
+	// The distance between the baselines of consecutive lines, in grid units.
+	fontLineSpacing = 10
+)
+
+// Contains the strokes of each printable ASCII character. Each stroke is a
+// polyline written as a sequence of two-digit points, where the first digit
+// is the x coordinate and the second is the y coordinate. Strokes are
+// separated by spaces.
+var fontGlyphs = [...]string{
+	' ':  "",
+	'!':  "2825 2322",
+	'"':  "1816 3836",
+	'#':  "1218 3238 0646 0444",
+	'$':  "473818070615354443321203 2921",
+	'%':  "0248 0718 3243",
+	'&':  "42071828370403123244",
+	'\'': "2826",
+	'(':  "38262432",
+	')':  "18262412",
+	'*':  "2327 0644 0446",
+	'+':  "2327 0545",
+	',':  "2311",
+	'-':  "0545",
+	'.':  "2322",
+	'/':  "0248",
+	'0':  "183847433212030718 0347",
+	'1':  "162822 1232",
+	'2':  "07183847460242",
+	'3':  "0718384746354443321203 2535",
+	'4':  "32380444",
+	'5':  "480805354443321203",
+	'6':  "473818070312324344351504",
+	'7':  "084822",
+	'8':  "183847463515060718 1504031232434435",
+	'9':  "463515060718384743321203",
+	':':  "2625 2322",
+	';':  "2625 2311",
+	'<':  "470543",
+	'=':  "0444 0646",
+	'>':  "074503",
+	'?':  "0718384746352524 2322",
+	'@':  "433212030718384744 443525243344",
+	'A':  "022842 1535",
+	'B':  "02083847463505 3544433202",
+	'C':  "4738180703123243",
+	'D':  "02082846442202",
+	'E':  "48080242 0535",
+	'F':  "480802 0535",
+	'G':  "47381807031232434525",
+	'H':  "0208 4248 0545",
+	'I':  "1838 2822 1232",
+	'J':  "1848 3833221203",
+	'K':  "0208 4804 1542",
+	'L':  "080242",
+	'M':  "0208254842",
+	'N':  "02084248",
+	'O':  "183847433212030718",
+	'P':  "02083847463505",
+	'Q':  "183847433212030718 2442",
+	'R':  "02083847463505 2542",
+	'S':  "473818070615354443321203",
+	'T':  "0848 2822",
+	'U':  "080312324348",
+	'V':  "082248",
+	'W':  "0812253248",
+	'X':  "0842 0248",
+	'Y':  "082548 2522",
+	'Z':  "08480242",
+	'[':  "38181232",
+	'\\': "0842",
+	']':  "18383212",
+	'^':  "062846",
+	'_':  "0141",
+	'`':  "1827",
+	'a':  "4642 4536160503123243",
+	'b':  "0802 0312324345361605",
+	'c':  "4536160503123243",
+	'd':  "4842 4536160503123243",
+	'e':  "04444536160503123243",
+	'f':  "22273848 1636",
+	'g':  "46413010 4536160503123243",
+	'h':  "0802 0516364542",
+	'i':  "2622 2728",
+	'j':  "36312010 3738",
+	'k':  "0802 4604 2542",
+	'l':  "18282332",
+	'm':  "0602 05162522 25364542",
+	'n':  "0602 0516364542",
+	'o':  "163645433212030516",
+	'p':  "0600 0516364543321203",
+	'q':  "4640 4536160503123243",
+	'r':  "0602 05163645",
+	's':  "45361605143443321203",
+	't':  "28233242 1636",
+	'u':  "0603123243 4642",
+	'v':  "062246",
+	'w':  "0612243246",
+	'x':  "0642 0246",
+	'y':  "0624 462010",
+	'z':  "06460242",
+	'{':  "38282615242232",
+	'|':  "2821",
+	'}':  "18282635242212",
+	'~':  "0516253445",
+}
+
+// Returns the strokes making up the glyph for the given character. Characters
+// without a glyph are drawn as a question mark.
+func glyphStrokes(r rune) []string {
+	if (r < 0) || (int(r) >= len(fontGlyphs)) || ((r != ' ') &&
+		(fontGlyphs[r] == "")) {
+		r = '?'
+	}
+	return strings.Fields(fontGlyphs[r])
+}
+
+// An instruction telling the turtle to write text using the built-in font.
+type writeTextInstruction struct {
+	text   string
+	height float64
+}
+
+func (n *writeTextInstruction) String() string {
+	return fmt.Sprintf("Write text %q with height %f", n.text, n.height)
+}
+
+func (n *writeTextInstruction) Apply(s *TurtleState, c Canvas) error {
+	x, y, angle := s.getPosition()
+	radians := angle * math.Pi / 180.0
+	cos := math.Cos(radians)
+	sin := math.Sin(radians)
+	scale := n.height * s.position.stepScale / fontCapHeight
+	// Converts a point in grid units, relative to the start of the text, to
+	// canvas coordinates.
+	toCanvas := func(forward, left float64) (float64, float64) {
+		forward *= scale
+		left *= scale
+		return x + forward*cos - left*sin, y + forward*sin + left*cos
+	}
+	line := 0
+	column := 0
+	for _, r := range n.text {
+		if r == '\n' {
+			line++
+			column = 0
+			continue
+		}
+		originX := float64(column * fontAdvance)
+		originY := float64(-fontBaseline - line*fontLineSpacing)
+		for _, stroke := range glyphStrokes(r) {
+			var prevX, prevY float64
+			for i := 0; (i + 1) < len(stroke); i += 2 {
+				pointX, pointY := toCanvas(originX+float64(stroke[i]-'0'),
+					originY+float64(stroke[i+1]-'0'))
+				if i != 0 {
+					dx := pointX - prevX
+					dy := pointY - prevY
+					e := c.DrawLine(prevX, prevY,
+						math.Atan2(dy, dx)*180.0/math.Pi, math.Hypot(dx, dy))
+					if e != nil {
+						return fmt.Errorf("Failed drawing text: %w", e)
+					}
+				}
+				prevX, prevY = pointX, pointY
+			}
+		}
+		column++
+	}
+	endX, endY := toCanvas(float64(column*fontAdvance),
+		float64(-line*fontLineSpacing))
+	s.moveTo(endX, endY)
+	return nil
+}
+
+// Adds an instruction to write the given text using a built-in single-stroke
+// font, in the direction the turtle is facing. The turtle's position is the
+// left end of the first line's baseline, and height is the height of capital
+// letters, which is multiplied by the turtle's step scale. Each '\n' starts a
+// new line below the previous one. The text is drawn using the current style
+// even if the pen is up, so labels can be placed without drawing a line to
+// them. Afterwards, the turtle is moved to the end of the last line of text,
+// without drawing.
+func (t *Turtle) WriteText(s string, height float64) {
+	t.addOp(opWriteText, s, height)
+}
@@ -0,0 +1,163 @@
+package turtle_graphics
+
+import (
+	"math"
+	"testing"
+)
+
+// Returns the paths drawn by a turtle writing the text with the given height,
+// starting at the given position and heading.
+func getTextPaths(t *testing.T, options TurtleOptions, text string,
+	height float64) []Path {
+	turtle := NewTurtleWithOptions(options)
+	turtle.WriteText(text, height)
+	return getTestPaths(t, turtle, 0.01)
+}
+
+// Checks that the paths contain the expected polylines, within 1e-9. The end
+// of each line the text draws is computed from its angle and length, so may
+// differ from the start of the next line by a rounding error, splitting a
+// polyline into several paths. Such paths are joined before checking them.
+func checkTextPaths(t *testing.T, paths []Path, expected [][]Point) {
+	joined := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		if len(joined) != 0 {
+			previous := &(joined[len(joined)-1])
+			end := previous.Points[len(previous.Points)-1]
+			if (math.Abs(end.X-p.Points[0].X) < 1e-9) &&
+				(math.Abs(end.Y-p.Points[0].Y) < 1e-9) {
+				previous.Points = append(previous.Points, p.Points[1:]...)
+				continue
+			}
+		}
+		joined = append(joined, Path{
+			Points: append([]Point(nil), p.Points...),
+		})
+	}
+	if len(joined) != len(expected) {
+		t.Errorf("Expected %d paths, got %d: %v", len(expected), len(joined),
+			joined)
+		return
+	}
+	for i, p := range joined {
+		checkPathPoints(t, p, expected[i])
+	}
+}
+
+func TestTextLayout(t *testing.T) {
+	// Capital letters are as tall as the given height, and the turtle's
+	// position is the left end of the baseline.
+	paths := getTextPaths(t, TurtleOptions{}, "L-", 3)
+	checkTextPaths(t, paths, [][]Point{
+		{{0, 3}, {0, 0}, {2, 0}},
+		{{3, 1.5}, {5, 1.5}},
+	})
+
+	// The text is written in the direction the turtle faces, and each new
+	// line starts below the previous one.
+	paths = getTextPaths(t, TurtleOptions{X: 1, Y: 1, Heading: 90}, "-\n -",
+		6)
+	checkTextPaths(t, paths, [][]Point{
+		{{-2, 1}, {-2, 5}},
+		{{8, 7}, {8, 11}},
+	})
+}
+
+func TestTextFinalPosition(t *testing.T) {
+	tests := []struct {
+		text string
+		x, y float64
+	}{
+		{"", 0, 0},
+		{"ab", 12, 0},
+		{"a\nbc", 12, -10},
+		{"abc\n", 0, -10},
+	}
+	for _, test := range tests {
+		turtle := NewTurtle()
+		turtle.WriteText(test.text, 6)
+		_, state := getFinalState(t, turtle)
+		if (math.Abs(state.x-test.x) > 1e-9) ||
+			(math.Abs(state.y-test.y) > 1e-9) || (state.heading != 0) {
+			t.Errorf("Expected writing %q to end at (%f, %f), heading 0, "+
+				"got (%f, %f), heading %f", test.text, test.x, test.y,
+				state.x, state.y, state.heading)
+		}
+	}
+}
+
+func TestTextUnknownCharacters(t *testing.T) {
+	question := getTextPaths(t, TurtleOptions{}, "?", 6)
+	if len(question) == 0 {
+		t.Fatalf("The question mark didn't draw anything")
+	}
+	expected := make([][]Point, len(question))
+	for i, p := range question {
+		expected[i] = p.Points
+	}
+	for _, text := range []string{"é", "\t", "\x01", "\x7f"} {
+		paths := getTextPaths(t, TurtleOptions{}, text, 6)
+		if len(paths) != len(expected) {
+			t.Errorf("Expected %q to draw %d paths, like a question mark, "+
+				"got %d", text, len(expected), len(paths))
+			continue
+		}
+		for i, p := range paths {
+			checkPathPoints(t, p, expected[i])
+		}
+	}
+	// Spaces don't draw anything, but still take up room.
+	paths := getTextPaths(t, TurtleOptions{}, " -", 6)
+	checkTextPaths(t, paths, [][]Point{{{6, 3}, {10, 3}}})
+}
+
+func TestTextExtents(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.WriteText("T", 6)
+	c, _ := getFinalState(t, turtle)
+	minX, minY, maxX, maxY := c.GetExtents()
+	if (minX > 0) || (minX < -0.01) || (maxX < 4) || (maxX > 4.01) {
+		t.Errorf("Expected the text to span x from 0 to 4, got %f to %f",
+			minX, maxX)
+	}
+	if (minY > 0) || (minY < -0.01) || (maxY < 6) || (maxY > 6.01) {
+		t.Errorf("Expected the text to span y from 0 to 6, got %f to %f",
+			minY, maxY)
+	}
+}
+
+func TestTextPenUp(t *testing.T) {
+	// Text is drawn even with the pen up, but the turtle doesn't draw a line
+	// to the end of the text.
+	turtle := NewTurtle()
+	turtle.PenUp()
+	turtle.WriteText("-", 6)
+	turtle.MoveForward(1)
+	paths := getTestPaths(t, turtle, 0.01)
+	checkTextPaths(t, paths, [][]Point{{{0, 3}, {4, 3}}})
+}
+
+func TestTextStepScale(t *testing.T) {
+	// The text's height is scaled like the distances the turtle moves, but
+	// only within the pushed position.
+	turtle := NewTurtle()
+	turtle.PushPosition()
+	turtle.ScaleStep(0.5)
+	turtle.WriteText("-", 6)
+	turtle.PopPosition()
+	turtle.PenUp()
+	turtle.MoveForward(10)
+	turtle.WriteText("-", 6)
+	paths := getTestPaths(t, turtle, 0.01)
+	checkTextPaths(t, paths, [][]Point{{{0, 1.5}, {2, 1.5}},
+		{{10, 3}, {14, 3}}})
+
+	turtle = NewTurtle()
+	turtle.ScaleStep(2)
+	turtle.WriteText("ab", 1)
+	_, state := getFinalState(t, turtle)
+	if (math.Abs(state.x-4) > 1e-9) || (math.Abs(state.y) > 1e-9) {
+		t.Errorf("Expected scaled text to end at (4, 0), got (%f, %f)",
+			state.x, state.y)
+	}
+}
@@ -0,0 +1,459 @@
+package turtle_graphics
+
+// This file contains functions for inspecting and editing the list of
+// instructions recorded by a Turtle.
+
+import (
+	"fmt"
+)
+
+// Identifies the type of an instruction in a turtle's instruction list.
+type InstructionKind int
+
+const (
+	// An instruction that isn't built in, added using Turtle.Add.
+	KindCustom InstructionKind = iota
+	// Added by Turtle.MoveForward. Operands: distance.
+	KindMoveForward
+	// Added by Turtle.Turn. Operands: degrees.
+	KindTurn
+	// Added by Turtle.SetStyle. No operands; see InstructionDescriptor.Style.
+	KindSetStyle
+	// Added by Turtle.MoveArc. Operands: radius, degrees.
+	KindMoveArc
+	// Added by Turtle.GoTo. Operands: x, y.
+	KindGoTo
+	// Added by Turtle.JumpTo. Operands: x, y.
+	KindJumpTo
+	// Added by Turtle.SetHeading. Operands: degrees.
+	KindSetHeading
+	// Added by Turtle.FaceTowards. Operands: x, y.
+	KindFaceTowards
+	// Added by Turtle.Home. No operands.
+	KindHome
+	// Added by Turtle.PenUp. No operands.
+	KindPenUp
+	// Added by Turtle.PenDown. No operands.
+	KindPenDown
+	// Added by Turtle.PushPosition. No operands.
+	KindPushPosition
+	// Added by Turtle.PopPosition. No operands.
+	KindPopPosition
+	// Added by Turtle.TurnRandom. Operands: min, max.
+	KindTurnRandom
+	// Added by Turtle.MoveForwardRandom. Operands: min, max.
+	KindMoveForwardRandom
+	// Added by Turtle.Chance. Operands: probability. See
+	// InstructionDescriptor.Subprogram.
+	KindChance
+	// Added by Turtle.BeginFill. No operands; see InstructionDescriptor.Style
+	// and InstructionDescriptor.FillRule.
+	KindBeginFill
+	// Added by Turtle.EndFill. No operands.
+	KindEndFill
+	// Added by Turtle.QuadraticBezier. Operands: controlForward, controlLeft,
+	// endForward, endLeft.
+	KindQuadraticBezier
+	// Added by Turtle.CubicBezier. Operands: control1Forward, control1Left,
+	// control2Forward, control2Left, endForward, endLeft.
+	KindCubicBezier
+	// Added by Turtle.WriteText. Operands: height. See
+	// InstructionDescriptor.Text.
+	KindWriteText
+	// Added by Turtle.Dot. Operands: diameter.
+	KindDot
+	// Added by Turtle.Stamp. No operands; see InstructionDescriptor.Subprogram.
+	KindStamp
+	// Added by Turtle.ScaleStep. Operands: factor.
+	KindScaleStep
+	// Added by Turtle.SetLineWidth. Operands: width.
+	KindSetLineWidth
+	// Added by Turtle.ScaleLineWidth. Operands: factor.
+	KindScaleLineWidth
+	// Added by Turtle.Call. Operands: scale. See
+	// InstructionDescriptor.Subprogram.
+	KindCall
+	// Added by Turtle.CallRecursive. Operands: depth, scale. See
+	// InstructionDescriptor.Subprogram and InstructionDescriptor.Base.
+	KindCallRecursive
+	// Added by Turtle.Recurse. Operands: scale.
+	KindRecurse
+	// Added by Turtle.Transform, Turtle.Scale or Turtle.Mirror. Operands: the
+	// XX, YX, XY, YY, X0 and Y0 fields of the AffineTransform.
+	KindTransform
+)
+
+func (k InstructionKind) String() string {
+	switch k {
+	case KindCustom:
+		return "custom"
+	case KindMoveForward:
+		return "move forward"
+	case KindTurn:
+		return "turn"
+	case KindSetStyle:
+		return "set style"
+	case KindMoveArc:
+		return "move arc"
+	case KindGoTo:
+		return "go to"
+	case KindJumpTo:
+		return "jump to"
+	case KindSetHeading:
+		return "set heading"
+	case KindFaceTowards:
+		return "face towards"
+	case KindHome:
+		return "home"
+	case KindPenUp:
+		return "pen up"
+	case KindPenDown:
+		return "pen down"
+	case KindPushPosition:
+		return "push position"
+	case KindPopPosition:
+		return "pop position"
+	case KindTurnRandom:
+		return "turn random"
+	case KindMoveForwardRandom:
+		return "move forward random"
+	case KindChance:
+		return "chance"
+	case KindBeginFill:
+		return "begin fill"
+	case KindEndFill:
+		return "end fill"
+	case KindQuadraticBezier:
+		return "quadratic Bezier"
+	case KindCubicBezier:
+		return "cubic Bezier"
+	case KindWriteText:
+		return "write text"
+	case KindDot:
+		return "dot"
+	case KindStamp:
+		return "stamp"
+	case KindScaleStep:
+		return "scale step"
+	case KindSetLineWidth:
+		return "set line width"
+	case KindScaleLineWidth:
+		return "scale line width"
+	case KindCall:
+		return "call"
+	case KindCallRecursive:
+		return "call recursive"
+	case KindRecurse:
+		return "recurse"
+	case KindTransform:
+		return "transform"
+	}
+	return fmt.Sprintf("unknown instruction kind %d", int(k))
+}
+
+// Describes a single instruction in a turtle's instruction list.
+type InstructionDescriptor struct {
+	// The type of the instruction.
+	Kind InstructionKind
+	// The instruction's numeric operands, in the same order as the arguments
+	// to the Turtle method that adds the instruction. Empty for instructions
+	// without numeric operands. Operands always use the default TurtleOptions,
+	// regardless of the options the instruction was added with: angles are in
+	// degrees, turns and headings are counter-clockwise, and the radii of arcs
+	// and the sideways offsets of curves are distances to the turtle's left.
+	// So, for example, all three are negated for instructions added to a
+	// turtle using LogoOrientation.
+	Operands []float64
+	// The stroke style set by a KindSetStyle instruction, or the fill style of
+	// a KindBeginFill instruction. nil for all other kinds.
+	Style StrokeStyle
+	// The fill rule used by a KindBeginFill instruction.
+	FillRule FillRule
+	// The subprogram run by a KindChance instruction, the shape drawn by a
+	// KindStamp instruction, the turtle called by a KindCall instruction, or
+	// the procedure carried out by a KindCallRecursive instruction. nil for
+	// all other kinds.
+	Subprogram *Turtle
+	// The base turtle drawn by a KindCallRecursive instruction once the
+	// recursion reaches its depth. nil for all other kinds, and may be nil for
+	// KindCallRecursive.
+	Base *Turtle
+	// The text written by a KindWriteText instruction. Empty for all other
+	// kinds.
+	Text string
+	// The instruction itself. It can be passed to Turtle.Add or Turtle.Insert
+	// to copy it to a different position or a different turtle.
+	Instruction Instruction
+}
+
+// Returns a descriptor for the given instruction.
+func describeInstruction(n Instruction) InstructionDescriptor {
+	d := InstructionDescriptor{
+		Kind:        KindCustom,
+		Instruction: n,
+	}
+	builtIn, ok := n.(turtleInstruction)
+	if ok {
+		builtIn.describe(&d)
+	}
+	return d
+}
+
+func (d *InstructionDescriptor) String() string {
+	return d.Instruction.String()
+}
+
+func (n *moveForwardInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindMoveForward
+	d.Operands = []float64{n.distance}
+}
+
+func (n *turnInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindTurn
+	d.Operands = []float64{n.degrees}
+}
+
+func (n *setStyleInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindSetStyle
+	d.Style = n.style
+}
+
+func (n *moveArcInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindMoveArc
+	d.Operands = []float64{n.radius, n.degrees}
+}
+
+func (n *goToInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindGoTo
+	if !n.draw {
+		d.Kind = KindJumpTo
+	}
+	d.Operands = []float64{n.x, n.y}
+}
+
+func (n *setHeadingInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindSetHeading
+	d.Operands = []float64{n.degrees}
+}
+
+func (n *faceTowardsInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindFaceTowards
+	d.Operands = []float64{n.x, n.y}
+}
+
+func (n *homeInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindHome
+}
+
+func (n *setPenInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindPenDown
+	if n.up {
+		d.Kind = KindPenUp
+	}
+}
+
+func (n *pushPositionInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindPushPosition
+}
+
+func (n *popPositionInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindPopPosition
+}
+
+func (n *turnRandomInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindTurnRandom
+	d.Operands = []float64{n.min, n.max}
+}
+
+func (n *moveForwardRandomInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindMoveForwardRandom
+	d.Operands = []float64{n.min, n.max}
+}
+
+func (n *chanceInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindChance
+	d.Operands = []float64{n.probability}
+	d.Subprogram = n.subprogram
+}
+
+func (n *beginFillInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindBeginFill
+	d.Style = n.style
+	d.FillRule = n.rule
+}
+
+func (n *endFillInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindEndFill
+}
+
+func (n *bezierInstruction) describe(d *InstructionDescriptor) {
+	if n.cubic {
+		d.Kind = KindCubicBezier
+		d.Operands = append([]float64(nil), n.offsets[:]...)
+		return
+	}
+	d.Kind = KindQuadraticBezier
+	d.Operands = append([]float64(nil), n.offsets[0:4]...)
+}
+
+func (n *writeTextInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindWriteText
+	d.Operands = []float64{n.height}
+	d.Text = n.text
+}
+
+func (n *dotInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindDot
+	d.Operands = []float64{n.diameter}
+}
+
+func (n *stampInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindStamp
+	d.Subprogram = n.shape
+}
+
+func (n *scaleStepInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindScaleStep
+	d.Operands = []float64{n.factor}
+}
+
+func (n *setLineWidthInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindSetLineWidth
+	d.Operands = []float64{n.width}
+}
+
+func (n *scaleLineWidthInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindScaleLineWidth
+	d.Operands = []float64{n.factor}
+}
+
+func (n *callInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindCall
+	d.Operands = []float64{n.scale}
+	d.Subprogram = n.sub
+}
+
+func (n *callRecursiveInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindCallRecursive
+	d.Operands = []float64{float64(n.depth), n.scale}
+	d.Subprogram = n.procedure.body
+	d.Base = n.procedure.base
+}
+
+func (n *recurseInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindRecurse
+	d.Operands = []float64{n.scale}
+}
+
+func (n *transformInstruction) describe(d *InstructionDescriptor) {
+	d.Kind = KindTransform
+	d.Operands = []float64{n.m.XX, n.m.YX, n.m.XY, n.m.YY, n.m.X0, n.m.Y0}
+}
+
+// Used to step through a turtle's instructions in order. Obtained by calling
+// Turtle.Instructions. The turtle must not be modified while an iterator is in
+// use.
+type InstructionIterator struct {
+	// A copy of the turtle's instruction list when the iterator was created.
+	instructions instructionList
+	cursor       instructionCursor
+	// False until Next is first called.
+	started bool
+}
+
+// Advances the iterator to the next instruction. Returns false if no
+// instructions remain. Must be called before the first call to Descriptor.
+func (it *InstructionIterator) Next() bool {
+	if !it.started {
+		it.started = true
+		return it.cursor.valid()
+	}
+	if !it.cursor.valid() {
+		return false
+	}
+	it.cursor.next()
+	return it.cursor.valid()
+}
+
+// Returns the index of the current instruction in the turtle's instruction
+// list.
+func (it *InstructionIterator) Index() int {
+	if !it.started {
+		return -1
+	}
+	return it.cursor.index
+}
+
+// Returns a description of the current instruction.
+func (it *InstructionIterator) Descriptor() InstructionDescriptor {
+	return describeInstruction(it.cursor.instruction())
+}
+
+// Returns an iterator over the turtle's instructions. Typical usage:
+//
+//	it := t.Instructions()
+//	for it.Next() {
+//		d := it.Descriptor()
+//		...
+//	}
+func (t *Turtle) Instructions() *InstructionIterator {
+	it := &InstructionIterator{
+		instructions: t.instructions,
+	}
+	it.cursor = it.instructions.cursor()
+	return it
+}
+
+// Returns the number of instructions the turtle has recorded.
+func (t *Turtle) Len() int {
+	if t == nil {
+		return 0
+	}
+	return t.instructions.len()
+}
+
+// Discards all but the first n instructions. Returns an error if n is negative
+// or greater than the number of instructions.
+func (t *Turtle) Truncate(n int) error {
+	if (n < 0) || (n > t.instructions.len()) {
+		return fmt.Errorf("Can't truncate to %d instructions: the turtle "+
+			"has %d instructions", n, t.instructions.len())
+	}
+	t.instructions.truncate(n)
+	return nil
+}
+
+// Inserts the given instructions before the instruction at the given index.
+// An index equal to Len() appends the instructions to the end of the list.
+func (t *Turtle) Insert(index int, instructions ...Instruction) error {
+	if (index < 0) || (index > t.instructions.len()) {
+		return fmt.Errorf("Can't insert at index %d: the turtle has %d "+
+			"instructions", index, t.instructions.len())
+	}
+	for _, n := range instructions {
+		if n == nil {
+			return fmt.Errorf("Can't insert a nil instruction")
+		}
+	}
+	t.instructions.insert(index, instructions)
+	return nil
+}
+
+// Removes the instruction at the given index.
+func (t *Turtle) Remove(index int) error {
+	if (index < 0) || (index >= t.instructions.len()) {
+		return fmt.Errorf("Can't remove instruction %d: the turtle has %d "+
+			"instructions", index, t.instructions.len())
+	}
+	t.instructions.remove(index)
+	return nil
+}
+
+// Removes the most recently added instruction. Returns an error if the turtle
+// has no instructions.
+func (t *Turtle) Undo() error {
+	if t.instructions.len() == 0 {
+		return fmt.Errorf("Can't undo: the turtle has no instructions")
+	}
+	return t.Truncate(t.instructions.len() - 1)
+}
@@ -3,26 +3,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/yalue/l_system"
 	"github.com/yalue/turtle_graphics"
 	"os"
+	"os/signal"
 )
 
-// A function that is intended to issue some instruction to a
-// turtle_graphics.Turtle instance. This doesn't return any errors since the
-// Turtle.MoveForward(...), PushPosition(), etc, don't return errors. Used when
-// mapping string bytes to turtle movements.
-type TurtleInstruction func(t *turtle_graphics.Turtle)
-
 // Maintains all the information needed to associate an L-system-generated
 // string with turtle movements.
 type LSystemTurtle struct {
 	L *l_system.LSystem
 	// Contains 256 entries; one corresponding to each possible byte generated
 	// by the L-system string. nil entries mean the corresponding byte does
-	// nothing.
-	CharMapping []TurtleInstruction
+	// nothing. Entries may be built-in instructions, obtained from another
+	// turtle using Turtle.Instructions, or user-defined ones.
+	CharMapping []turtle_graphics.Instruction
 }
 
 // Returns a new L-system turtle, initializing the L-system with the given
@@ -31,47 +28,64 @@ type LSystemTurtle struct {
 func NewLSystemTurtle(initialString []byte) *LSystemTurtle {
 	return &LSystemTurtle{
 		L:           l_system.NewLSystem(initialString),
-		CharMapping: make([]TurtleInstruction, 256),
+		CharMapping: make([]turtle_graphics.Instruction, 256),
 	}
 }
 
-// Returns a turtle that follows the instructions specified by the L-system.
-func (s *LSystemTurtle) GetTurtle() (*turtle_graphics.Turtle, error) {
-	t := turtle_graphics.NewTurtle()
+// Implements the turtle_graphics.InstructionSource interface, issuing the
+// instructions specified by the L-system to the given turtle.
+func (s *LSystemTurtle) Generate(t *turtle_graphics.Turtle) error {
 	chars := s.L.GetValue()
-	var f TurtleInstruction
-	// The very simple loop where we apply the specified instructions.
+	// The very simple loop where we add the specified instructions. Add
+	// ignores nil instructions.
 	for _, c := range chars {
-		f = s.CharMapping[c]
-		if f != nil {
-			f(t)
-		}
+		t.Add(s.CharMapping[c])
 	}
 	// This can't return an error for now, but it's always nice to have it as
 	// an option in the future.
-	return t, nil
-}
-
-func moveForward(t *turtle_graphics.Turtle) {
-	t.MoveForward(1.0)
+	return nil
 }
 
-func turnLeft(t *turtle_graphics.Turtle) {
-	t.Turn(90.0)
+// Returns the instructions recorded by the given turtle, in order. Used to
+// obtain built-in instructions for the CharMapping.
+func getInstructions(t *turtle_graphics.Turtle) []turtle_graphics.Instruction {
+	toReturn := make([]turtle_graphics.Instruction, 0, t.Len())
+	it := t.Instructions()
+	for it.Next() {
+		toReturn = append(toReturn, it.Descriptor().Instruction)
+	}
+	return toReturn
 }
 
-func turnRight(t *turtle_graphics.Turtle) {
-	t.Turn(-90.0)
+// Prints the percentage of the rendering that has been completed, or only the
+// number of instructions if the total isn't known yet.
+func printProgress(done, total int) {
+	if total < 0 {
+		fmt.Printf("\rRendering: %d instructions done", done)
+		return
+	}
+	fmt.Printf("\rRendering: %.1f%% done", 100.0*float64(done)/float64(total))
+	if done == total {
+		fmt.Printf("\n")
+	}
 }
 
-// Saves the given image as a PNG file with the given name.
-func saveImage(t *turtle_graphics.Turtle, name string) error {
+// Saves the image drawn by the given instructions as a PNG file with the given
+// name. The instructions are rendered as they're generated, without storing
+// them. Rendering can be canceled by pressing Ctrl+C.
+func saveImage(source turtle_graphics.InstructionSource, name string) error {
 	f, e := os.Create(name)
 	if e != nil {
 		return fmt.Errorf("Couldn't create %s: %s", name, e)
 	}
 	defer f.Close()
-	e = turtle_graphics.SaveTurtleAsPNG(t, 1000, f)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts := &turtle_graphics.RenderOptions{
+		Progress:      printProgress,
+		CheckInterval: 65536,
+	}
+	e = turtle_graphics.SaveStreamAsPNGContext(ctx, source, 1000, f, opts)
 	if e != nil {
 		return fmt.Errorf("Failed rendering turtle to %s: %s", name, e)
 	}
@@ -87,6 +101,13 @@ func run() int {
 	s := NewLSystemTurtle([]byte("F"))
 	s.L.SetProduction('F', []byte("F+G"))
 	s.L.SetProduction('G', []byte("F-G"))
+	t := turtle_graphics.NewTurtle()
+	t.MoveForward(1.0)
+	t.Turn(90.0)
+	t.Turn(-90.0)
+	instructions := getInstructions(t)
+	moveForward, turnLeft, turnRight := instructions[0], instructions[1],
+		instructions[2]
 	s.CharMapping['F'] = moveForward
 	s.CharMapping['G'] = moveForward
 	s.CharMapping['-'] = turnRight
@@ -101,14 +122,9 @@ func run() int {
 		}
 	}
 
-	// Get the turtle and save it to a PNG image.
+	// Save the turtle's drawing to a PNG image.
 	fmt.Printf("Length of instruction string: %d bytes.\n", s.L.GetSize())
-	t, e := s.GetTurtle()
-	if e != nil {
-		fmt.Printf("Error getting the dragon-curve turtle: %s\n", e)
-		return 1
-	}
-	e = saveImage(t, "dragon_curve.png")
+	e = saveImage(s, "dragon_curve.png")
 	if e != nil {
 		fmt.Printf("Error saving dragon curve to a PNG: %s\n", e)
 		return 1
@@ -0,0 +1,140 @@
+package logo
+
+// This file contains the tokenizer for Logo source code.
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Identifies the different kinds of tokens in Logo source code.
+type tokenKind int
+
+const (
+	// A name such as FD or a procedure name. Always upper case.
+	wordToken tokenKind = iota
+	// A variable reference such as :SIZE. The text excludes the colon and is
+	// always upper case.
+	variableToken
+	// A quoted word such as "SIZE, used to name variables in MAKE. The text
+	// excludes the quote and is always upper case.
+	quotedToken
+	// A numeric literal.
+	numberToken
+	// A single-character operator or bracket, such as + or [.
+	symbolToken
+	// Marks the end of the source code.
+	endToken
+)
+
+// A single token from Logo source code.
+type token struct {
+	kind tokenKind
+	text string
+	// Only set for numberToken.
+	value float64
+	// The 1-based line and column where the token starts.
+	line, column int
+}
+
+func (t *token) String() string {
+	switch t.kind {
+	case variableToken:
+		return ":" + t.text
+	case quotedToken:
+		return "\"" + t.text
+	case endToken:
+		return "end of input"
+	}
+	return t.text
+}
+
+// Returns true if c can appear in a word, variable name or number.
+func isWordChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || (c == '_') ||
+		(c == '.') || (c == '?')
+}
+
+// Splits Logo source code into tokens. Comments start with a semicolon and
+// continue to the end of the line.
+func tokenize(source string) ([]token, error) {
+	toReturn := make([]token, 0, len(source)/2)
+	chars := []rune(source)
+	line := 1
+	column := 1
+	i := 0
+	for i < len(chars) {
+		c := chars[i]
+		if c == '\n' {
+			line++
+			column = 1
+			i++
+			continue
+		}
+		if unicode.IsSpace(c) {
+			column++
+			i++
+			continue
+		}
+		if c == ';' {
+			for (i < len(chars)) && (chars[i] != '\n') {
+				i++
+			}
+			continue
+		}
+		start := i
+		t := token{
+			line:   line,
+			column: column,
+		}
+		switch {
+		case strings.ContainsRune("[]()+-*/<>=", c):
+			t.kind = symbolToken
+			t.text = string(c)
+			i++
+		case (c == ':') || (c == '"'):
+			i++
+			for (i < len(chars)) && isWordChar(chars[i]) {
+				i++
+			}
+			if i == start+1 {
+				return nil, newError(line, column, "Expected a name after %q",
+					string(c))
+			}
+			t.kind = variableToken
+			if c == '"' {
+				t.kind = quotedToken
+			}
+			t.text = strings.ToUpper(string(chars[start+1 : i]))
+		case unicode.IsDigit(c) || (c == '.'):
+			for (i < len(chars)) && isWordChar(chars[i]) {
+				i++
+			}
+			t.kind = numberToken
+			t.text = string(chars[start:i])
+			v, e := strconv.ParseFloat(t.text, 64)
+			if e != nil {
+				return nil, newError(line, column, "Invalid number %q", t.text)
+			}
+			t.value = v
+		case isWordChar(c):
+			for (i < len(chars)) && isWordChar(chars[i]) {
+				i++
+			}
+			t.kind = wordToken
+			t.text = strings.ToUpper(string(chars[start:i]))
+		default:
+			return nil, newError(line, column, "Unexpected character %q",
+				string(c))
+		}
+		column += i - start
+		toReturn = append(toReturn, t)
+	}
+	toReturn = append(toReturn, token{
+		kind:   endToken,
+		line:   line,
+		column: column,
+	})
+	return toReturn, nil
+}
@@ -0,0 +1,289 @@
+// This package implements an interpreter for a small subset of the Logo
+// programming language, which issues instructions to a turtle_graphics.Turtle.
+//
+// Supported commands are FD (FORWARD), BK (BACK), LT (LEFT), RT (RIGHT),
+// PU (PENUP), PD (PENDOWN), HOME, SETH (SETHEADING), SETXY, REPEAT, IF,
+// IFELSE, MAKE, STOP and procedures defined using TO ... END, which may take
+// parameters and call themselves recursively. Expressions support +, -, *, /,
+// <, >, =, parentheses, variables (e.g. :SIZE) and the functions REPCOUNT,
+// SQRT, ABS, SIN and COS. Names are case insensitive, and comments start with
+// a semicolon. Programs must be run on turtles using Logo's conventions, such
+// as those returned by NewTurtle, which start out facing up and turn
+// clockwise for RT.
+//
+// For example, this draws a square:
+//
+//	TO SQUARE :SIZE
+//	  REPEAT 4 [FD :SIZE RT 90]
+//	END
+//	SQUARE 100
+package logo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/yalue/turtle_graphics"
+)
+
+// The default maximum number of nested procedure calls allowed when running a
+// Program.
+const DefaultMaxDepth = 1000
+
+// The type of all errors returned when parsing or running a Logo program.
+// Contains the location in the source code where the error occurred.
+type Error struct {
+	// The 1-based line and column in the source code.
+	Line, Column int
+	// Describes the problem.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Returns a new *Error with a formatted message.
+func newError(line, column int, format string, args ...interface{}) *Error {
+	return &Error{
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Used internally to unwind the interpreter's call stack when executing STOP.
+var errStop = errors.New("STOP")
+
+// Returned by Run if the turtle doesn't use LogoOrientation and Degrees.
+var ErrTurtleOptions = errors.New("Logo programs must be run using a " +
+	"turtle with LogoOrientation and Degrees")
+
+// Returns a new turtle that Logo programs can be run on, starting at the
+// origin and facing up.
+func NewTurtle() *turtle_graphics.Turtle {
+	return turtle_graphics.NewTurtleWithOptions(turtle_graphics.TurtleOptions{
+		AngleUnit:   turtle_graphics.Degrees,
+		Orientation: turtle_graphics.LogoOrientation,
+	})
+}
+
+// Holds a parsed Logo program, which can be run any number of times.
+type Program struct {
+	statements []statement
+	// The maximum number of nested procedure calls allowed before Run returns
+	// an error. Set to DefaultMaxDepth by Parse.
+	MaxDepth int
+}
+
+// Parses the given Logo source code. Returns an *Error if the source code
+// contains a syntax error.
+func Parse(source string) (*Program, error) {
+	tokens, e := tokenize(source)
+	if e != nil {
+		return nil, e
+	}
+	p := &parser{
+		tokens:     tokens,
+		position:   0,
+		procedures: make(map[string]*procedure),
+	}
+	statements, e := p.parseProgram()
+	if e != nil {
+		return nil, e
+	}
+	return &Program{
+		statements: statements,
+		MaxDepth:   DefaultMaxDepth,
+	}, nil
+}
+
+// Runs the program, adding its instructions to the given turtle, which must
+// use Logo's conventions: created using NewTurtle, or using TurtleOptions with
+// LogoOrientation and Degrees. Returns ErrTurtleOptions, without adding any
+// instructions, if it doesn't. Otherwise, returns an *Error if the program
+// fails, in which case the turtle may contain some of the program's
+// instructions.
+func (p *Program) Run(t *turtle_graphics.Turtle) error {
+	options := t.Options()
+	if (options.Orientation != turtle_graphics.LogoOrientation) ||
+		(options.AngleUnit != turtle_graphics.Degrees) {
+		return ErrTurtleOptions
+	}
+	in := &interpreter{
+		turtle:       t,
+		maxDepth:     p.MaxDepth,
+		scopes:       []map[string]float64{make(map[string]float64)},
+		repeatCounts: make([]float64, 0, 8),
+	}
+	e := in.executeBlock(p.statements)
+	if e == errStop {
+		return nil
+	}
+	return e
+}
+
+// Parses and runs the given Logo source code, adding its instructions to the
+// given turtle, which must use Logo's conventions. A convenience wrapper
+// around Parse and Program.Run.
+func Run(source string, t *turtle_graphics.Turtle) error {
+	p, e := Parse(source)
+	if e != nil {
+		return e
+	}
+	return p.Run(t)
+}
+
+// Holds the state of a running Logo program.
+type interpreter struct {
+	turtle   *turtle_graphics.Turtle
+	maxDepth int
+	// Variables, with the global scope first and the innermost procedure's
+	// parameters last. Like most Logo dialects, variables are dynamically
+	// scoped.
+	scopes []map[string]float64
+	// The current iteration of each enclosing REPEAT, starting at 1. The
+	// innermost REPEAT is last.
+	repeatCounts []float64
+}
+
+// Returns the value of the named variable, searching from the innermost scope
+// outward.
+func (in *interpreter) lookup(name string) (float64, bool) {
+	for i := len(in.scopes) - 1; i >= 0; i-- {
+		v, ok := in.scopes[i][name]
+		if ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Runs a list of statements in order.
+func (in *interpreter) executeBlock(statements []statement) error {
+	for _, s := range statements {
+		e := s.execute(in)
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *commandStatement) execute(in *interpreter) error {
+	args := make([]float64, len(s.args))
+	for i, arg := range s.args {
+		v, e := arg.evaluate(in)
+		if e != nil {
+			return e
+		}
+		args[i] = v
+	}
+	t := in.turtle
+	switch s.command.name {
+	case "FORWARD":
+		t.MoveForward(args[0])
+	case "BACK":
+		t.MoveForward(-args[0])
+	case "LEFT":
+		t.Turn(-args[0])
+	case "RIGHT":
+		t.Turn(args[0])
+	case "PENUP":
+		t.PenUp()
+	case "PENDOWN":
+		t.PenDown()
+	case "HOME":
+		t.Home()
+	case "SETHEADING":
+		t.SetHeading(args[0])
+	case "SETXY":
+		t.GoTo(args[0], args[1])
+	default:
+		return newError(s.pos.line, s.pos.column, "Unknown command %s",
+			s.command.name)
+	}
+	return nil
+}
+
+func (s *repeatStatement) execute(in *interpreter) error {
+	count, e := s.count.evaluate(in)
+	if e != nil {
+		return e
+	}
+	if count < 0 {
+		return newError(s.pos.line, s.pos.column, "REPEAT count can't be "+
+			"negative (got %g)", count)
+	}
+	in.repeatCounts = append(in.repeatCounts, 0)
+	top := len(in.repeatCounts) - 1
+	for i := 1; i <= int(count); i++ {
+		in.repeatCounts[top] = float64(i)
+		e = in.executeBlock(s.body)
+		if e != nil {
+			break
+		}
+	}
+	in.repeatCounts = in.repeatCounts[0:top]
+	return e
+}
+
+func (s *ifStatement) execute(in *interpreter) error {
+	condition, e := s.condition.evaluate(in)
+	if e != nil {
+		return e
+	}
+	if condition != 0 {
+		return in.executeBlock(s.body)
+	}
+	return in.executeBlock(s.elseBody)
+}
+
+func (s *makeStatement) execute(in *interpreter) error {
+	v, e := s.value.evaluate(in)
+	if e != nil {
+		return e
+	}
+	// Assign to the innermost existing variable with the name, or create a
+	// global one if none exists.
+	for i := len(in.scopes) - 1; i >= 0; i-- {
+		_, ok := in.scopes[i][s.name]
+		if ok {
+			in.scopes[i][s.name] = v
+			return nil
+		}
+	}
+	in.scopes[0][s.name] = v
+	return nil
+}
+
+func (s *stopStatement) execute(in *interpreter) error {
+	return errStop
+}
+
+func (s *callStatement) execute(in *interpreter) error {
+	// The global scope doesn't count towards the depth.
+	if len(in.scopes) > in.maxDepth {
+		return newError(s.pos.line, s.pos.column, "Exceeded the maximum "+
+			"procedure call depth (%d) calling %s", in.maxDepth,
+			s.procedure.name)
+	}
+	scope := make(map[string]float64, len(s.args))
+	for i, arg := range s.args {
+		v, e := arg.evaluate(in)
+		if e != nil {
+			return e
+		}
+		scope[s.procedure.params[i]] = v
+	}
+	// REPCOUNT only refers to REPEATs within the current procedure.
+	savedRepeatCounts := in.repeatCounts
+	in.repeatCounts = make([]float64, 0, 8)
+	in.scopes = append(in.scopes, scope)
+	e := in.executeBlock(s.procedure.body)
+	in.scopes = in.scopes[0 : len(in.scopes)-1]
+	in.repeatCounts = savedRepeatCounts
+	if e == errStop {
+		return nil
+	}
+	return e
+}
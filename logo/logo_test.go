@@ -0,0 +1,392 @@
+package logo
+
+import (
+	"errors"
+	"github.com/yalue/turtle_graphics"
+	"math"
+	"strings"
+	"testing"
+)
+
+// An instruction that records the turtle's position and heading when it's
+// carried out.
+type recordStateInstruction struct {
+	x, y, heading float64
+}
+
+func (n *recordStateInstruction) Apply(s *turtle_graphics.TurtleState,
+	c turtle_graphics.Canvas) error {
+	n.x, n.y = s.Position()
+	n.heading = s.Heading()
+	return nil
+}
+
+func (n *recordStateInstruction) String() string {
+	return "Record the turtle's state"
+}
+
+// Holds the result of running a Logo program in a test.
+type testResult struct {
+	turtle *turtle_graphics.Turtle
+	// The turtle's position at the end of the program.
+	x, y float64
+	// The turtle's heading at the end of the program, in degrees using
+	// Logo's conventions: 0 faces up and angles increase clockwise.
+	heading float64
+}
+
+// Runs the source code, failing the test if it doesn't succeed.
+func runTestProgram(t *testing.T, source string) *testResult {
+	turtle := NewTurtle()
+	e := Run(source, turtle)
+	if e != nil {
+		t.Fatalf("Failed running %q: %s", source, e)
+	}
+	record := &recordStateInstruction{}
+	turtle.Add(record)
+	e = turtle.RenderToCanvas(turtle_graphics.NewDummyCanvas())
+	if e != nil {
+		t.Fatalf("Failed rendering the output of %q: %s", source, e)
+	}
+	heading := math.Mod(90-record.heading, 360)
+	if heading < 0 {
+		heading += 360
+	}
+	return &testResult{
+		turtle:  turtle,
+		x:       record.x,
+		y:       record.y,
+		heading: heading,
+	}
+}
+
+// Returns the number of instructions of the given kind added to the turtle.
+func (r *testResult) count(kind turtle_graphics.InstructionKind) int {
+	toReturn := 0
+	it := r.turtle.Instructions()
+	for it.Next() {
+		if it.Descriptor().Kind == kind {
+			toReturn++
+		}
+	}
+	return toReturn
+}
+
+// Fails the test if the program didn't end at the given position and heading.
+func (r *testResult) check(t *testing.T, source string, x, y,
+	heading float64) {
+	const tolerance = 1e-9
+	if (math.Abs(r.x-x) > tolerance) || (math.Abs(r.y-y) > tolerance) {
+		t.Errorf("%q ended at (%f, %f), expected (%f, %f)", source, r.x,
+			r.y, x, y)
+	}
+	difference := math.Abs(r.heading - heading)
+	if (difference > tolerance) && (math.Abs(difference-360) > tolerance) {
+		t.Errorf("%q ended with heading %f, expected %f", source, r.heading,
+			heading)
+	}
+}
+
+// Checks that err is an *Error at the given position whose message contains
+// the given text.
+func checkError(t *testing.T, source string, err error, line, column int,
+	message string) {
+	if err == nil {
+		t.Errorf("Didn't get an error for %q", source)
+		return
+	}
+	t.Logf("Got expected error for %q: %s", source, err)
+	var logoError *Error
+	if !errors.As(err, &logoError) {
+		t.Errorf("Expected an *Error for %q, got %T", source, err)
+		return
+	}
+	if (logoError.Line != line) || (logoError.Column != column) {
+		t.Errorf("Expected the error for %q at line %d, column %d, got "+
+			"line %d, column %d", source, line, column, logoError.Line,
+			logoError.Column)
+	}
+	if !strings.Contains(logoError.Message, message) {
+		t.Errorf("Expected the error for %q to contain %q", source, message)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	source := "fd 10 ; A comment\n  make \"size :Size*2.5\n[rt 90]"
+	tokens, e := tokenize(source)
+	if e != nil {
+		t.Fatalf("Failed tokenizing: %s", e)
+	}
+	expected := []token{
+		{kind: wordToken, text: "FD", line: 1, column: 1},
+		{kind: numberToken, text: "10", value: 10, line: 1, column: 4},
+		{kind: wordToken, text: "MAKE", line: 2, column: 3},
+		{kind: quotedToken, text: "SIZE", line: 2, column: 8},
+		{kind: variableToken, text: "SIZE", line: 2, column: 14},
+		{kind: symbolToken, text: "*", line: 2, column: 19},
+		{kind: numberToken, text: "2.5", value: 2.5, line: 2, column: 20},
+		{kind: symbolToken, text: "[", line: 3, column: 1},
+		{kind: wordToken, text: "RT", line: 3, column: 2},
+		{kind: numberToken, text: "90", value: 90, line: 3, column: 5},
+		{kind: symbolToken, text: "]", line: 3, column: 7},
+		{kind: endToken, line: 3, column: 8},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected),
+			len(tokens), tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Token %d: expected %+v, got %+v", i, expected[i],
+				tokens[i])
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		source       string
+		line, column int
+		message      string
+	}{
+		{"FD 10\n  RT 1.2.3", 2, 6, "Invalid number \"1.2.3\""},
+		{"FD 10 ; Comment\nFD @", 2, 4, "Unexpected character \"@\""},
+		{"MAKE \" 3", 1, 6, "Expected a name after"},
+		{"FD :", 1, 4, "Expected a name after"},
+	}
+	for _, test := range tests {
+		_, e := tokenize(test.source)
+		checkError(t, test.source, e, test.line, test.column, test.message)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source       string
+		line, column int
+		message      string
+	}{
+		{"FD 10\nJUMP 3", 2, 1, "Unknown procedure JUMP"},
+		{"FD", 1, 3, "Expected a value, got end of input"},
+		{"REPEAT 4 FD 10", 1, 10, "Expected \"[\", got FD"},
+		{"REPEAT 4 [FD 10", 1, 16, "Missing \"]\""},
+		{"FD (1 + 2", 1, 10, "Expected \")\", got end of input"},
+		{"TO SQUARE\nFD 10\n", 3, 1, "Missing END for procedure SQUARE"},
+		{"TO FD\nEND", 1, 4, "Can't redefine built-in FD"},
+		{"TO A\nEND\nTO A\nEND", 3, 4, "Procedure A is already defined"},
+		{"REPEAT 2 [TO A END]", 1, 11, "defined at the top level"},
+		{"FD 1 END", 1, 6, "END without TO"},
+		{"MAKE X 3", 1, 6, "Expected a quoted variable name"},
+		{"SQRT 4", 1, 1, "Don't know what to do with the result of SQRT"},
+		{"TO A\nEND\nFD A", 3, 4, "Procedure A doesn't output a value"},
+		{"10", 1, 1, "Expected a command, got 10"},
+	}
+	for _, test := range tests {
+		_, e := Parse(test.source)
+		checkError(t, test.source, e, test.line, test.column, test.message)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		source       string
+		line, column int
+		message      string
+	}{
+		{"FD 1\nFD 10 / (2 - 2)", 2, 7, "Division by zero"},
+		{"FD :SIZE", 1, 4, "Variable :SIZE has no value"},
+		{"RT SQRT -1", 1, 4, "SQRT of negative number -1"},
+		{"FD REPCOUNT", 1, 4, "REPCOUNT used outside of REPEAT"},
+		{"REPEAT 2 - 3 [FD 1]", 1, 1, "REPEAT count can't be negative"},
+		// REPCOUNT doesn't refer to REPEATs in the calling procedure.
+		{"TO A\n  FD REPCOUNT\nEND\nREPEAT 2 [A]", 2, 6,
+			"REPCOUNT used outside of REPEAT"},
+		// Parameters are only visible while the procedure runs.
+		{"TO A :X\nEND\nA 3\nFD :X", 4, 4, "Variable :X has no value"},
+	}
+	for _, test := range tests {
+		e := Run(test.source, NewTurtle())
+		checkError(t, test.source, e, test.line, test.column, test.message)
+	}
+}
+
+func TestMovement(t *testing.T) {
+	tests := []struct {
+		source        string
+		x, y, heading float64
+	}{
+		{"", 0, 0, 0},
+		{"FD 10", 0, 10, 0},
+		{"BK 10", 0, -10, 0},
+		{"RT 90 FD 10", 10, 0, 90},
+		{"LT 90 FORWARD 10", -10, 0, 270},
+		{"SETH 90 FD 5", 5, 0, 90},
+		{"SETHEADING 180 FD 5", 0, -5, 180},
+		{"SETXY 3 4 FD 1", 3, 5, 0},
+		{"RT 45 FD 10 HOME", 0, 0, 0},
+		{"PU FD 10 PD RIGHT 90 BACK 2", -2, 10, 90},
+		{"fd 2 * (3 + 4) - 10 / 5", 0, 12, 0},
+		{"FD -(2 + 3) * -2", 0, 10, 0},
+		{"RT 30 FD (SQRT 16) + ABS -1", 2.5, 5 * math.Sqrt(3) / 2, 30},
+		// As in other Logo dialects, a function's argument extends as far
+		// as possible.
+		{"FD SQRT 16 + 9", 0, 5, 0},
+		{"SETXY SIN 30 COS 60", 0.5, 0.5, 0},
+	}
+	for _, test := range tests {
+		r := runTestProgram(t, test.source)
+		r.check(t, test.source, test.x, test.y, test.heading)
+	}
+}
+
+func TestTurtleOptions(t *testing.T) {
+	wrongOptions := []turtle_graphics.TurtleOptions{
+		{},
+		{
+			AngleUnit:   turtle_graphics.Radians,
+			Orientation: turtle_graphics.LogoOrientation,
+		},
+	}
+	for _, options := range wrongOptions {
+		turtle := turtle_graphics.NewTurtleWithOptions(options)
+		e := Run("FD 1", turtle)
+		if !errors.Is(e, ErrTurtleOptions) {
+			t.Errorf("Expected %q running with options %v, got %v",
+				ErrTurtleOptions, options, e)
+		} else {
+			t.Logf("Got expected error: %s", e)
+		}
+		if turtle.Len() != 0 {
+			t.Errorf("Run added instructions to a turtle with the wrong " +
+				"options")
+		}
+	}
+
+	// HOME returns to the turtle's starting position and heading.
+	turtle := turtle_graphics.NewTurtleWithOptions(
+		turtle_graphics.TurtleOptions{
+			Orientation: turtle_graphics.LogoOrientation,
+			X:           1,
+			Y:           2,
+			Heading:     90,
+		})
+	source := "FD 1 LT 45 FD 3 HOME FD 1"
+	e := Run(source, turtle)
+	if e != nil {
+		t.Fatalf("Failed running %q: %s", source, e)
+	}
+	record := &recordStateInstruction{}
+	turtle.Add(record)
+	e = turtle.RenderToCanvas(turtle_graphics.NewDummyCanvas())
+	if e != nil {
+		t.Fatalf("Failed rendering the output of %q: %s", source, e)
+	}
+	if (math.Abs(record.x-2) > 1e-9) || (math.Abs(record.y-2) > 1e-9) ||
+		(math.Abs(record.heading) > 1e-9) {
+		t.Errorf("%q ended at (%f, %f) with heading %f, expected (2, 2) "+
+			"facing along the X axis", source, record.x, record.y,
+			record.heading)
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	source := "REPEAT 4 [FD 10 RT 90]"
+	r := runTestProgram(t, source)
+	r.check(t, source, 0, 0, 0)
+	if r.count(turtle_graphics.KindMoveForward) != 4 {
+		t.Errorf("Expected %q to move forward 4 times", source)
+	}
+
+	// REPCOUNT refers to the innermost REPEAT, starting at 1.
+	source = "REPEAT 3 [REPEAT 2 [FD REPCOUNT] FD REPCOUNT * 10]"
+	r = runTestProgram(t, source)
+	r.check(t, source, 0, 69, 0)
+
+	source = "REPEAT 0 [FD 10] REPEAT 2.7 [FD 1]"
+	r = runTestProgram(t, source)
+	r.check(t, source, 0, 2, 0)
+}
+
+func TestIfAndMake(t *testing.T) {
+	tests := []struct {
+		source string
+		y      float64
+	}{
+		{"MAKE \"X 5 IF :X > 3 [FD :X]", 5},
+		{"MAKE \"X 5 IF :X < 3 [FD :X]", 0},
+		{"MAKE \"X 2 IFELSE :X = 2 [FD 1] [FD 2]", 1},
+		{"MAKE \"X 2 IFELSE :X = 3 [FD 1] [FD 2]", 2},
+		{"MAKE \"X 1 MAKE \"X :X + 1 FD :X", 2},
+		{"MAKE \"X 1 REPEAT 3 [MAKE \"X :X * 2] FD :X", 8},
+		// MAKE inside a procedure assigns to an existing global, or creates
+		// one if there isn't a variable with the name.
+		{"TO A\n MAKE \"X 3\n MAKE \"Y 4\nEND\nMAKE \"X 1\nA\nFD :X + :Y", 7},
+		// Variables are dynamically scoped, so MAKE assigns to the
+		// parameter, leaving the global unchanged.
+		{"TO A :X\n MAKE \"X 10\n FD :X\nEND\nMAKE \"X 1\nA 2\nFD :X", 11},
+	}
+	for _, test := range tests {
+		r := runTestProgram(t, test.source)
+		r.check(t, test.source, 0, test.y, 0)
+	}
+}
+
+func TestProcedures(t *testing.T) {
+	// Procedures may be called before they're defined, and may call
+	// themselves.
+	source := `
+SPIRAL 4
+TO SPIRAL :N
+  IF :N < 1 [STOP]
+  FD :N
+  SPIRAL :N - 1
+  ; Only reached as each call returns.
+  FD 100
+END`
+	r := runTestProgram(t, source)
+	r.check(t, source, 0, 410, 0)
+
+	// STOP only returns from the innermost procedure, even inside REPEAT.
+	source = `
+TO A
+  REPEAT 10 [FD 1 IF REPCOUNT = 3 [STOP]]
+  FD 100
+END
+A A`
+	r = runTestProgram(t, source)
+	r.check(t, source, 0, 6, 0)
+
+	// STOP at the top level ends the program.
+	source = "FD 1 STOP FD 2"
+	r = runTestProgram(t, source)
+	r.check(t, source, 0, 1, 0)
+}
+
+func TestRecursionLimit(t *testing.T) {
+	source := `TO DOWN :N
+  IF :N > 0 [FD 1 DOWN :N - 1]
+END
+DOWN 10`
+	p, e := Parse(source)
+	if e != nil {
+		t.Fatalf("Failed parsing %q: %s", source, e)
+	}
+	// DOWN 10 calls DOWN 11 times in total.
+	p.MaxDepth = 11
+	e = p.Run(NewTurtle())
+	if e != nil {
+		t.Errorf("Failed running %q with a maximum depth of 11: %s", source,
+			e)
+	}
+	p.MaxDepth = 10
+	e = p.Run(NewTurtle())
+	checkError(t, source, e, 2, 19, "Exceeded the maximum procedure call "+
+		"depth (10) calling DOWN")
+
+	// Infinite recursion stops at the default depth.
+	source = "TO LOOP\n  FD 1\n  LOOP\nEND\nLOOP"
+	turtle := NewTurtle()
+	e = Run(source, turtle)
+	checkError(t, source, e, 3, 3, "Exceeded the maximum procedure call "+
+		"depth (1000)")
+}
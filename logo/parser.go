@@ -0,0 +1,624 @@
+package logo
+
+// This file contains the parser, which converts tokens into a tree of
+// statements and expressions that can be executed by the interpreter.
+
+import (
+	"math"
+)
+
+// An expression that evaluates to a number.
+type expression interface {
+	evaluate(in *interpreter) (float64, error)
+}
+
+// A statement that can be executed, typically issuing turtle instructions.
+type statement interface {
+	execute(in *interpreter) error
+}
+
+// A numeric literal.
+type numberExpression struct {
+	value float64
+}
+
+// A reference to a variable or procedure parameter.
+type variableExpression struct {
+	name string
+	pos  token
+}
+
+// A binary arithmetic or comparison operation. Comparisons evaluate to 1 if
+// true and 0 if false.
+type binaryExpression struct {
+	operator    string
+	left, right expression
+	pos         token
+}
+
+// Negates the value of another expression.
+type negateExpression struct {
+	operand expression
+}
+
+// Calls one of the built-in numeric functions, such as SQRT.
+type functionExpression struct {
+	name string
+	args []expression
+	pos  token
+}
+
+// Information about a built-in command that takes a fixed number of numeric
+// arguments.
+type commandInfo struct {
+	// The canonical name of the command, e.g. "FORWARD" for both FD and
+	// FORWARD.
+	name     string
+	argCount int
+}
+
+// Maps names and abbreviations of built-in commands to information about
+// them.
+var commands = map[string]commandInfo{
+	"FD":         {"FORWARD", 1},
+	"FORWARD":    {"FORWARD", 1},
+	"BK":         {"BACK", 1},
+	"BACK":       {"BACK", 1},
+	"LT":         {"LEFT", 1},
+	"LEFT":       {"LEFT", 1},
+	"RT":         {"RIGHT", 1},
+	"RIGHT":      {"RIGHT", 1},
+	"PU":         {"PENUP", 0},
+	"PENUP":      {"PENUP", 0},
+	"PD":         {"PENDOWN", 0},
+	"PENDOWN":    {"PENDOWN", 0},
+	"HOME":       {"HOME", 0},
+	"SETH":       {"SETHEADING", 1},
+	"SETHEADING": {"SETHEADING", 1},
+	"SETXY":      {"SETXY", 2},
+}
+
+// Maps the names of built-in functions usable in expressions to the number of
+// arguments they take.
+var functions = map[string]int{
+	"REPCOUNT": 0,
+	"SQRT":     1,
+	"ABS":      1,
+	"SIN":      1,
+	"COS":      1,
+}
+
+// Names that can't be used for user-defined procedures, in addition to the
+// commands and functions.
+var keywords = map[string]bool{
+	"TO":     true,
+	"END":    true,
+	"REPEAT": true,
+	"IF":     true,
+	"IFELSE": true,
+	"MAKE":   true,
+	"STOP":   true,
+}
+
+// Runs one of the built-in commands, such as FD.
+type commandStatement struct {
+	command commandInfo
+	args    []expression
+	pos     token
+}
+
+// Runs a block of statements a number of times.
+type repeatStatement struct {
+	count expression
+	body  []statement
+	pos   token
+}
+
+// Runs one of two blocks of statements depending on whether a condition is
+// nonzero. The elseBody is nil for IF statements.
+type ifStatement struct {
+	condition expression
+	body      []statement
+	elseBody  []statement
+}
+
+// Assigns a value to a variable.
+type makeStatement struct {
+	name  string
+	value expression
+}
+
+// Returns from the current procedure.
+type stopStatement struct{}
+
+// Calls a user-defined procedure.
+type callStatement struct {
+	procedure *procedure
+	args      []expression
+	pos       token
+}
+
+// A user-defined procedure, created using TO ... END.
+type procedure struct {
+	name   string
+	params []string
+	body   []statement
+}
+
+// Holds the state needed to convert tokens into statements.
+type parser struct {
+	tokens []token
+	// The index of the next token to consume.
+	position int
+	// All user-defined procedures, keyed by name. Populated before parsing
+	// any statements, so procedures may be called before they're defined.
+	procedures map[string]*procedure
+}
+
+// Returns the next token without consuming it.
+func (p *parser) peek() *token {
+	return &(p.tokens[p.position])
+}
+
+// Consumes and returns the next token. Never advances past the end token.
+func (p *parser) next() *token {
+	t := &(p.tokens[p.position])
+	if t.kind != endToken {
+		p.position++
+	}
+	return t
+}
+
+// Returns true if the next token is the given symbol.
+func (p *parser) peekSymbol(symbol string) bool {
+	t := p.peek()
+	return (t.kind == symbolToken) && (t.text == symbol)
+}
+
+// Consumes the next token, returning an error if it isn't the given symbol.
+func (p *parser) expectSymbol(symbol string) error {
+	t := p.next()
+	if (t.kind != symbolToken) || (t.text != symbol) {
+		return newError(t.line, t.column, "Expected %q, got %s", symbol,
+			t.String())
+	}
+	return nil
+}
+
+// Finds the name and parameters of every TO ... END definition, so that the
+// number of arguments to each procedure is known while parsing calls.
+func (p *parser) findProcedures() error {
+	for i := 0; i < len(p.tokens); i++ {
+		t := &(p.tokens[i])
+		if (t.kind != wordToken) || (t.text != "TO") {
+			continue
+		}
+		i++
+		nameToken := &(p.tokens[i])
+		if nameToken.kind != wordToken {
+			return newError(nameToken.line, nameToken.column,
+				"Expected a procedure name after TO, got %s",
+				nameToken.String())
+		}
+		name := nameToken.text
+		_, isCommand := commands[name]
+		_, isFunction := functions[name]
+		if isCommand || isFunction || keywords[name] {
+			return newError(nameToken.line, nameToken.column,
+				"Can't redefine built-in %s", name)
+		}
+		if p.procedures[name] != nil {
+			return newError(nameToken.line, nameToken.column,
+				"Procedure %s is already defined", name)
+		}
+		params := make([]string, 0, 4)
+		for p.tokens[i+1].kind == variableToken {
+			i++
+			params = append(params, p.tokens[i].text)
+		}
+		p.procedures[name] = &procedure{
+			name:   name,
+			params: params,
+		}
+	}
+	return nil
+}
+
+// Parses a TO ... END definition, starting at the TO token.
+func (p *parser) parseDefinition() error {
+	p.next()
+	proc := p.procedures[p.next().text]
+	p.position += len(proc.params)
+	body := make([]statement, 0, 16)
+	for {
+		t := p.peek()
+		if t.kind == endToken {
+			return newError(t.line, t.column, "Missing END for procedure %s",
+				proc.name)
+		}
+		if (t.kind == wordToken) && (t.text == "END") {
+			p.next()
+			break
+		}
+		s, e := p.parseStatement()
+		if e != nil {
+			return e
+		}
+		body = append(body, s)
+	}
+	proc.body = body
+	return nil
+}
+
+// Parses a bracketed list of statements.
+func (p *parser) parseBlock() ([]statement, error) {
+	e := p.expectSymbol("[")
+	if e != nil {
+		return nil, e
+	}
+	toReturn := make([]statement, 0, 8)
+	for !p.peekSymbol("]") {
+		if p.peek().kind == endToken {
+			t := p.peek()
+			return nil, newError(t.line, t.column, "Missing \"]\"")
+		}
+		s, e := p.parseStatement()
+		if e != nil {
+			return nil, e
+		}
+		toReturn = append(toReturn, s)
+	}
+	p.next()
+	return toReturn, nil
+}
+
+// Parses count expressions, used as arguments to a command or procedure.
+func (p *parser) parseArguments(count int) ([]expression, error) {
+	toReturn := make([]expression, count)
+	for i := range toReturn {
+		arg, e := p.parseExpression()
+		if e != nil {
+			return nil, e
+		}
+		toReturn[i] = arg
+	}
+	return toReturn, nil
+}
+
+// Parses a single statement.
+func (p *parser) parseStatement() (statement, error) {
+	t := p.next()
+	if t.kind != wordToken {
+		return nil, newError(t.line, t.column, "Expected a command, got %s",
+			t.String())
+	}
+	command, isCommand := commands[t.text]
+	if isCommand {
+		args, e := p.parseArguments(command.argCount)
+		if e != nil {
+			return nil, e
+		}
+		return &commandStatement{
+			command: command,
+			args:    args,
+			pos:     *t,
+		}, nil
+	}
+	switch t.text {
+	case "REPEAT":
+		count, e := p.parseExpression()
+		if e != nil {
+			return nil, e
+		}
+		body, e := p.parseBlock()
+		if e != nil {
+			return nil, e
+		}
+		return &repeatStatement{
+			count: count,
+			body:  body,
+			pos:   *t,
+		}, nil
+	case "IF", "IFELSE":
+		condition, e := p.parseExpression()
+		if e != nil {
+			return nil, e
+		}
+		body, e := p.parseBlock()
+		if e != nil {
+			return nil, e
+		}
+		var elseBody []statement
+		if t.text == "IFELSE" {
+			elseBody, e = p.parseBlock()
+			if e != nil {
+				return nil, e
+			}
+		}
+		return &ifStatement{
+			condition: condition,
+			body:      body,
+			elseBody:  elseBody,
+		}, nil
+	case "MAKE":
+		nameToken := p.next()
+		if nameToken.kind != quotedToken {
+			return nil, newError(nameToken.line, nameToken.column,
+				"Expected a quoted variable name after MAKE, got %s",
+				nameToken.String())
+		}
+		value, e := p.parseExpression()
+		if e != nil {
+			return nil, e
+		}
+		return &makeStatement{
+			name:  nameToken.text,
+			value: value,
+		}, nil
+	case "STOP":
+		return &stopStatement{}, nil
+	case "TO":
+		return nil, newError(t.line, t.column, "Procedures can only be "+
+			"defined at the top level")
+	case "END":
+		return nil, newError(t.line, t.column, "END without TO")
+	}
+	proc := p.procedures[t.text]
+	if proc == nil {
+		if _, isFunction := functions[t.text]; isFunction {
+			return nil, newError(t.line, t.column, "Don't know what to do "+
+				"with the result of %s", t.text)
+		}
+		return nil, newError(t.line, t.column, "Unknown procedure %s",
+			t.text)
+	}
+	args, e := p.parseArguments(len(proc.params))
+	if e != nil {
+		return nil, e
+	}
+	return &callStatement{
+		procedure: proc,
+		args:      args,
+		pos:       *t,
+	}, nil
+}
+
+// Parses an expression, including comparisons, which have the lowest
+// precedence.
+func (p *parser) parseExpression() (expression, error) {
+	left, e := p.parseSum()
+	if e != nil {
+		return nil, e
+	}
+	for p.peekSymbol("<") || p.peekSymbol(">") || p.peekSymbol("=") {
+		t := p.next()
+		right, e := p.parseSum()
+		if e != nil {
+			return nil, e
+		}
+		left = &binaryExpression{
+			operator: t.text,
+			left:     left,
+			right:    right,
+			pos:      *t,
+		}
+	}
+	return left, nil
+}
+
+// Parses addition and subtraction.
+func (p *parser) parseSum() (expression, error) {
+	left, e := p.parseProduct()
+	if e != nil {
+		return nil, e
+	}
+	for p.peekSymbol("+") || p.peekSymbol("-") {
+		t := p.next()
+		right, e := p.parseProduct()
+		if e != nil {
+			return nil, e
+		}
+		left = &binaryExpression{
+			operator: t.text,
+			left:     left,
+			right:    right,
+			pos:      *t,
+		}
+	}
+	return left, nil
+}
+
+// Parses multiplication and division.
+func (p *parser) parseProduct() (expression, error) {
+	left, e := p.parseUnary()
+	if e != nil {
+		return nil, e
+	}
+	for p.peekSymbol("*") || p.peekSymbol("/") {
+		t := p.next()
+		right, e := p.parseUnary()
+		if e != nil {
+			return nil, e
+		}
+		left = &binaryExpression{
+			operator: t.text,
+			left:     left,
+			right:    right,
+			pos:      *t,
+		}
+	}
+	return left, nil
+}
+
+// Parses a number, variable, function call, negation or parenthesized
+// expression.
+func (p *parser) parseUnary() (expression, error) {
+	t := p.next()
+	switch t.kind {
+	case numberToken:
+		return &numberExpression{
+			value: t.value,
+		}, nil
+	case variableToken:
+		return &variableExpression{
+			name: t.text,
+			pos:  *t,
+		}, nil
+	case symbolToken:
+		if t.text == "-" {
+			operand, e := p.parseUnary()
+			if e != nil {
+				return nil, e
+			}
+			return &negateExpression{
+				operand: operand,
+			}, nil
+		}
+		if t.text == "(" {
+			inner, e := p.parseExpression()
+			if e != nil {
+				return nil, e
+			}
+			e = p.expectSymbol(")")
+			if e != nil {
+				return nil, e
+			}
+			return inner, nil
+		}
+	case wordToken:
+		argCount, isFunction := functions[t.text]
+		if isFunction {
+			args, e := p.parseArguments(argCount)
+			if e != nil {
+				return nil, e
+			}
+			return &functionExpression{
+				name: t.text,
+				args: args,
+				pos:  *t,
+			}, nil
+		}
+		if p.procedures[t.text] != nil {
+			return nil, newError(t.line, t.column, "Procedure %s doesn't "+
+				"output a value", t.text)
+		}
+	}
+	return nil, newError(t.line, t.column, "Expected a value, got %s",
+		t.String())
+}
+
+// Parses a complete program, returning the top-level statements.
+func (p *parser) parseProgram() ([]statement, error) {
+	e := p.findProcedures()
+	if e != nil {
+		return nil, e
+	}
+	toReturn := make([]statement, 0, 64)
+	for p.peek().kind != endToken {
+		t := p.peek()
+		if (t.kind == wordToken) && (t.text == "TO") {
+			e = p.parseDefinition()
+			if e != nil {
+				return nil, e
+			}
+			continue
+		}
+		s, e := p.parseStatement()
+		if e != nil {
+			return nil, e
+		}
+		toReturn = append(toReturn, s)
+	}
+	return toReturn, nil
+}
+
+func (n *numberExpression) evaluate(in *interpreter) (float64, error) {
+	return n.value, nil
+}
+
+func (n *variableExpression) evaluate(in *interpreter) (float64, error) {
+	v, ok := in.lookup(n.name)
+	if !ok {
+		return 0, newError(n.pos.line, n.pos.column, "Variable :%s has no "+
+			"value", n.name)
+	}
+	return v, nil
+}
+
+// Converts a boolean to the 1 or 0 used as a truth value in Logo.
+func truthValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (n *binaryExpression) evaluate(in *interpreter) (float64, error) {
+	a, e := n.left.evaluate(in)
+	if e != nil {
+		return 0, e
+	}
+	b, e := n.right.evaluate(in)
+	if e != nil {
+		return 0, e
+	}
+	switch n.operator {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return 0, newError(n.pos.line, n.pos.column, "Division by zero")
+		}
+		return a / b, nil
+	case "<":
+		return truthValue(a < b), nil
+	case ">":
+		return truthValue(a > b), nil
+	case "=":
+		return truthValue(a == b), nil
+	}
+	return 0, newError(n.pos.line, n.pos.column, "Unknown operator %s",
+		n.operator)
+}
+
+func (n *negateExpression) evaluate(in *interpreter) (float64, error) {
+	v, e := n.operand.evaluate(in)
+	return -v, e
+}
+
+func (n *functionExpression) evaluate(in *interpreter) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, e := arg.evaluate(in)
+		if e != nil {
+			return 0, e
+		}
+		args[i] = v
+	}
+	switch n.name {
+	case "REPCOUNT":
+		if len(in.repeatCounts) == 0 {
+			return 0, newError(n.pos.line, n.pos.column, "REPCOUNT used "+
+				"outside of REPEAT")
+		}
+		return in.repeatCounts[len(in.repeatCounts)-1], nil
+	case "SQRT":
+		if args[0] < 0 {
+			return 0, newError(n.pos.line, n.pos.column, "SQRT of negative "+
+				"number %g", args[0])
+		}
+		return math.Sqrt(args[0]), nil
+	case "ABS":
+		return math.Abs(args[0]), nil
+	case "SIN":
+		return math.Sin(args[0] * math.Pi / 180.0), nil
+	case "COS":
+		return math.Cos(args[0] * math.Pi / 180.0), nil
+	}
+	return 0, newError(n.pos.line, n.pos.column, "Unknown function %s",
+		n.name)
+}
@@ -0,0 +1,82 @@
+// This defines a command-line program that renders a Logo program, using the
+// subset of Logo supported by github.com/yalue/turtle_graphics/logo, to a PNG
+// image.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/yalue/turtle_graphics"
+	"github.com/yalue/turtle_graphics/logo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Parses and runs the Logo program in the file at inputPath, saving the
+// resulting drawing to outputPath.
+func renderLogoFile(inputPath, outputPath string, pixelsTall,
+	maxDepth int) error {
+	source, e := ioutil.ReadFile(inputPath)
+	if e != nil {
+		return fmt.Errorf("Couldn't read %s: %w", inputPath, e)
+	}
+	program, e := logo.Parse(string(source))
+	if e != nil {
+		return fmt.Errorf("Failed parsing %s: %w", inputPath, e)
+	}
+	program.MaxDepth = maxDepth
+	t := logo.NewTurtle()
+	e = program.Run(t)
+	if e != nil {
+		return fmt.Errorf("Failed running %s: %w", inputPath, e)
+	}
+	f, e := os.Create(outputPath)
+	if e != nil {
+		return fmt.Errorf("Couldn't create %s: %w", outputPath, e)
+	}
+	defer f.Close()
+	e = turtle_graphics.SaveTurtleAsPNG(t, pixelsTall, f)
+	if e != nil {
+		return fmt.Errorf("Failed rendering turtle to %s: %w", outputPath, e)
+	}
+	return nil
+}
+
+func run() int {
+	var outputPath string
+	var pixelsTall, maxDepth int
+	flag.StringVar(&outputPath, "output", "", "The name of the PNG file to "+
+		"create. Defaults to the input file's name with a .png extension.")
+	flag.IntVar(&pixelsTall, "height", 1000, "The height of the image, in "+
+		"pixels.")
+	flag.IntVar(&maxDepth, "max_depth", logo.DefaultMaxDepth, "The maximum "+
+		"number of nested procedure calls.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] "+
+			"<file.logo>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return 1
+	}
+	inputPath := flag.Arg(0)
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) +
+			".png"
+	}
+	e := renderLogoFile(inputPath, outputPath, pixelsTall, maxDepth)
+	if e != nil {
+		fmt.Printf("Error: %s\n", e)
+		return 1
+	}
+	fmt.Printf("Created %s OK.\n", outputPath)
+	return 0
+}
+
+func main() {
+	os.Exit(run())
+}
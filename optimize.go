@@ -0,0 +1,345 @@
+package turtle_graphics
+
+// This file contains a peephole optimizer that shortens a turtle's list of
+// instructions without changing what the turtle draws.
+
+import (
+	"math"
+)
+
+// Returns true if a and b are both built-in color styles with the same color.
+// Other styles are never considered equal, since they may contain information
+// that isn't visible through the StrokeStyle interface.
+func sameStyle(a, b StrokeStyle) bool {
+	basicA, ok := a.(*basicStrokeStyle)
+	if !ok {
+		return false
+	}
+	basicB, ok := b.(*basicStrokeStyle)
+	if !ok {
+		return false
+	}
+	r1, g1, b1, a1 := basicA.c.RGBA()
+	r2, g2, b2, a2 := basicB.c.RGBA()
+	return (r1 == r2) && (g1 == g2) && (b1 == b2) && (a1 == a2)
+}
+
+// Returns true if the instruction only changes the turtle's position, heading
+// or pen state without drawing anything. Such instructions have no effect if
+// they're immediately followed by popping the position stack.
+func (o *optimizer) isPositionOnly(n Instruction) bool {
+	switch v := n.(type) {
+	case *turnInstruction, *setHeadingInstruction, *faceTowardsInstruction,
+		*setPenInstruction, *scaleStepInstruction:
+		return true
+	case *goToInstruction:
+		// Positions the turtle jumps to are part of a fill's outline.
+		return !v.draw && !o.mayBeFilling
+	case *transformInstruction:
+		// Transforming the frame may move the turtle on the canvas.
+		return !o.mayBeFilling
+	}
+	return false
+}
+
+// What the optimizer knows about the turtle's heading at a point in its
+// instructions.
+type headingState struct {
+	// The heading, in degrees. Only valid if known is set.
+	degrees float64
+	// Set if the heading is exactly degrees.
+	known bool
+	// Set if the heading is known to be greater than -360 and less than 360,
+	// as it is after any turn, even if its exact value isn't known. Always set
+	// if known is set.
+	normalized bool
+}
+
+// Returns the heading after turning by the given number of degrees, computed
+// exactly as turnInstruction and moveArcInstruction compute it.
+func (h headingState) turned(degrees float64) headingState {
+	if !h.known {
+		return headingState{normalized: true}
+	}
+	return headingState{
+		degrees:    math.Mod(degrees+h.degrees, 360.0),
+		known:      true,
+		normalized: true,
+	}
+}
+
+// Returns what is known about the heading after the given instruction, which
+// must not be PopPosition.
+func (h headingState) after(n Instruction) headingState {
+	switch v := n.(type) {
+	case *turnInstruction:
+		return h.turned(v.degrees)
+	case *moveArcInstruction:
+		return h.turned(v.degrees)
+	case *turnRandomInstruction:
+		return headingState{normalized: true}
+	case *setHeadingInstruction:
+		return headingState{
+			degrees:    math.Mod(v.degrees, 360.0),
+			known:      true,
+			normalized: true,
+		}
+	case *moveForwardInstruction, *moveForwardRandomInstruction,
+		*goToInstruction, *setPenInstruction, *setStyleInstruction,
+		*pushPositionInstruction, *beginFillInstruction, *endFillInstruction,
+		*dotInstruction, *stampInstruction, *writeTextInstruction,
+		*scaleStepInstruction, *setLineWidthInstruction,
+		*scaleLineWidthInstruction, *transformInstruction:
+		return h
+	}
+	return headingState{}
+}
+
+// Holds the state needed during a single pass of the optimizer.
+type optimizer struct {
+	// The optimized instructions so far.
+	out []Instruction
+	// The style in effect at the end of out, or nil if unknown, and the styles
+	// saved on the position stack.
+	style      StrokeStyle
+	styleStack []StrokeStyle
+	// Whether the turtle's pen is up at the end of out, and the pen states
+	// saved on the position stack.
+	penUp    bool
+	penStack []bool
+	// Set after a subprogram that may have changed the pen state or position
+	// stack, after which penUp can't be trusted.
+	penUnknown bool
+	// Set if a fill may be in progress at the end of out.
+	mayBeFilling bool
+	// The heading at the end of out, and the headings saved on the position
+	// stack. The heading is unknown at the start, since the instructions may
+	// be carried out by Chance, or copied by Append, starting from another
+	// turtle's heading.
+	heading      headingState
+	headingStack []headingState
+	// The heading before the last instruction in out, if it's a turn added
+	// by addTurn. Unknown if any other instruction has been added since.
+	headingBeforeTurn headingState
+}
+
+// Returns the last instruction in the optimized output, or nil if there is
+// none.
+func (o *optimizer) last() Instruction {
+	if len(o.out) == 0 {
+		return nil
+	}
+	return o.out[len(o.out)-1]
+}
+
+// Replaces the last instruction in the optimized output.
+func (o *optimizer) replaceLast(n Instruction) {
+	o.out[len(o.out)-1] = n
+}
+
+// Removes the last instruction from the optimized output.
+func (o *optimizer) removeLast() {
+	o.out[len(o.out)-1] = nil
+	o.out = o.out[0 : len(o.out)-1]
+}
+
+// Forgets the current style and the styles saved on the position stack, after
+// an instruction that may change the canvas's style without the turtle knowing
+// about it, or may push or pop positions.
+func (o *optimizer) forgetStyles() {
+	o.style = nil
+	for i := range o.styleStack {
+		o.styleStack[i] = nil
+	}
+}
+
+// Returns true if the pen is known to be up at the end of the output.
+func (o *optimizer) isPenUp() bool {
+	return o.penUp && !o.penUnknown
+}
+
+// Returns true if a move by the given distance is zero-length and can be
+// removed without changing the output. Canvases may draw a single point for a
+// zero-length line, so it can only be removed if the pen is up or the previous
+// instruction drew a line ending at the same point.
+func (o *optimizer) isInvisibleZeroMove(distance float64) bool {
+	if distance != 0 {
+		return false
+	}
+	if o.isPenUp() {
+		return true
+	}
+	previous, ok := o.last().(*moveForwardInstruction)
+	return ok && (previous.distance != 0)
+}
+
+// Appends a turn to the output, dropping it or merging it with the previous
+// turn if that leaves the heading exactly the same. Headings are rounded after
+// every turn, so, for example, turning by 360 degrees may change the heading's
+// last bits.
+func (o *optimizer) addTurn(n *turnInstruction) {
+	h := o.heading.turned(n.degrees)
+	if (n.degrees == 0) && o.heading.normalized {
+		return
+	}
+	if h.known && (h.degrees == o.heading.degrees) {
+		return
+	}
+	previous, ok := o.last().(*turnInstruction)
+	before := o.headingBeforeTurn
+	if ok && h.known && before.known {
+		if h.degrees == before.degrees {
+			o.removeLast()
+			o.heading = before
+			o.headingBeforeTurn = headingState{}
+			return
+		}
+		merged := &turnInstruction{
+			degrees: previous.degrees + n.degrees,
+		}
+		if before.turned(merged.degrees).degrees == h.degrees {
+			o.replaceLast(merged)
+			o.heading = h
+			return
+		}
+	}
+	o.out = append(o.out, n)
+	o.headingBeforeTurn = o.heading
+	o.heading = h
+}
+
+// Appends the next instruction to the output, merging it with or dropping it
+// in favor of the previous instruction where possible.
+func (o *optimizer) add(n Instruction) {
+	if _, ok := n.(*turnInstruction); !ok {
+		o.headingBeforeTurn = headingState{}
+	}
+	switch v := n.(type) {
+	case *moveForwardInstruction:
+		// Consecutive moves are never merged: the position after two moves
+		// may differ in its last bits from the position after a single move
+		// covering both, and canvases may draw two lines to different pixels
+		// than a single line.
+		if o.isInvisibleZeroMove(v.distance) {
+			return
+		}
+	case *turnInstruction:
+		o.addTurn(v)
+		return
+	case *setPenInstruction:
+		o.penUp = v.up
+		if _, ok := o.last().(*setPenInstruction); ok {
+			o.replaceLast(v)
+			return
+		}
+	case *setStyleInstruction:
+		if _, ok := o.last().(*setStyleInstruction); ok {
+			// The previous style was never used to draw anything.
+			o.replaceLast(v)
+			o.style = v.style
+			return
+		}
+		if sameStyle(o.style, v.style) {
+			return
+		}
+		o.style = v.style
+	case *pushPositionInstruction:
+		o.penStack = append(o.penStack, o.penUp)
+		o.styleStack = append(o.styleStack, o.style)
+		o.headingStack = append(o.headingStack, o.heading)
+	case *popPositionInstruction:
+		if len(o.penStack) != 0 {
+			o.penUp = o.penStack[len(o.penStack)-1]
+			o.penStack = o.penStack[0 : len(o.penStack)-1]
+		}
+		o.heading = headingState{}
+		if len(o.headingStack) != 0 {
+			o.heading = o.headingStack[len(o.headingStack)-1]
+			o.headingStack = o.headingStack[0 : len(o.headingStack)-1]
+		}
+		// Popping restores the style that was in effect when the position
+		// was pushed.
+		o.style = nil
+		if len(o.styleStack) != 0 {
+			o.style = o.styleStack[len(o.styleStack)-1]
+			o.styleStack = o.styleStack[0 : len(o.styleStack)-1]
+		}
+		// Changes to the position immediately before a pop are overwritten
+		// by it, and a push immediately followed by a pop does nothing.
+		for o.isPositionOnly(o.last()) {
+			o.removeLast()
+		}
+		if _, ok := o.last().(*pushPositionInstruction); ok {
+			o.removeLast()
+			return
+		}
+	case *chanceInstruction:
+		// The subprogram may change the style, pen or position stack, and
+		// random choices mean we don't know whether it will run.
+		o.forgetStyles()
+		o.penUnknown = true
+		o.mayBeFilling = true
+	case *stampInstruction, *callInstruction, *callRecursiveInstruction,
+		*recurseInstruction:
+		// These are drawn by a separate turtle, whose style changes are
+		// undone afterwards, but it may contain user-defined instructions.
+		o.forgetStyles()
+	case *beginFillInstruction:
+		o.mayBeFilling = true
+	case *endFillInstruction:
+		o.mayBeFilling = false
+	default:
+		// User-defined instructions may change the canvas's style directly.
+		if _, ok := n.(turtleInstruction); !ok {
+			o.forgetStyles()
+		}
+	}
+	if _, ok := n.(*popPositionInstruction); !ok {
+		o.heading = o.heading.after(n)
+	}
+	o.out = append(o.out, n)
+}
+
+// Shortens the turtle's list of instructions without changing what it draws,
+// or any of the positions and headings the turtle passes through. Removes
+// zero-length forward moves that don't draw anything, zero-degree turns after
+// the first turn, changes to the position that are undone by PopPosition,
+// pushes that are immediately popped, and style changes that don't affect any
+// drawing. Consecutive turns are only merged, and turns by multiples of 360
+// degrees only removed, after SetHeading, when the heading is known exactly
+// and the result doesn't differ in the last bits. Consecutive forward moves
+// are never merged, since that may change the turtle's position slightly, and
+// canvases such as RGBACanvas may draw two lines differently from a single
+// line covering both. User-defined instructions are never changed or removed,
+// and nothing is merged across them. Returns the number of instructions that
+// were removed.
+func (t *Turtle) Optimize() int {
+	originalLength := t.instructions.len()
+	instructions := t.instructions.decodeAll()
+	for {
+		o := &optimizer{
+			out:          make([]Instruction, 0, len(instructions)),
+			style:        nil,
+			styleStack:   make([]StrokeStyle, 0, 128),
+			penUp:        false,
+			penStack:     make([]bool, 0, 128),
+			penUnknown:   false,
+			mayBeFilling: false,
+			heading:      headingState{},
+			headingStack: make([]headingState, 0, 128),
+		}
+		for _, n := range instructions {
+			o.add(n)
+		}
+		// Removing some instructions can allow others to be merged, so
+		// repeat until nothing changes.
+		changed := len(o.out) != len(instructions)
+		instructions = o.out
+		if !changed {
+			break
+		}
+	}
+	t.instructions = newInstructionList(len(instructions))
+	t.instructions.addInstructions(instructions)
+	return originalLength - len(instructions)
+}
@@ -0,0 +1,274 @@
+package turtle_graphics
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Checks that optimizing the turtle doesn't change the PNG image it renders.
+func checkOptimizedPNG(t *testing.T, turtle *Turtle) {
+	var before, after bytes.Buffer
+	e := SaveTurtleAsPNG(turtle, 300, &before)
+	if e != nil {
+		t.Fatalf("Failed rendering turtle: %s", e)
+	}
+	turtle.Optimize()
+	e = SaveTurtleAsPNG(turtle, 300, &after)
+	if e != nil {
+		t.Fatalf("Failed rendering optimized turtle: %s", e)
+	}
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Errorf("Optimizing changed the rendered image")
+	}
+}
+
+func TestOptimizeKeepsPenDownMoves(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.Turn(315)
+	turtle.MoveForward(1.48)
+	turtle.MoveForward(0.74)
+	checkOptimizedPNG(t, turtle)
+}
+
+func TestOptimizeKeepsZeroDegreeArcs(t *testing.T) {
+	// A zero-degree arc moves the turtle by a tiny rounding error, which can
+	// change the pixels drawn afterwards.
+	turtle := NewTurtle()
+	turtle.MoveForward(0.73)
+	turtle.MoveArc(1.02, 0)
+	turtle.Turn(37)
+	turtle.MoveForward(2)
+	checkOptimizedPNG(t, turtle)
+}
+
+// A canvas that records every line and arc drawn on it, along with the style
+// it was drawn in, so that tests can check that two turtles draw exactly the
+// same things. Style changes that nothing is drawn with and zero-length lines
+// aren't recorded, since the optimizer may remove them without changing the
+// image. Zero is always recorded without a sign, for the same reason.
+type recordingCanvas struct {
+	style StrokeStyle
+	calls []string
+}
+
+func (c *recordingCanvas) SetStyle(s StrokeStyle) error {
+	c.style = s
+	return nil
+}
+
+func (c *recordingCanvas) DrawLine(x, y, angle, length float64) error {
+	if length == 0 {
+		return nil
+	}
+	c.calls = append(c.calls, fmt.Sprintf("line %v %v %v %v %v", x+0, y+0,
+		angle+0, length, c.style))
+	return nil
+}
+
+func (c *recordingCanvas) DrawArc(x, y, angle, radius, degrees float64) error {
+	c.calls = append(c.calls, fmt.Sprintf("arc %v %v %v %v %v %v", x+0, y+0,
+		angle+0, radius, degrees, c.style))
+	return nil
+}
+
+// An instruction that records the turtle's final position and heading on a
+// recordingCanvas.
+type recordStateTestInstruction struct{}
+
+func (n *recordStateTestInstruction) Apply(s *TurtleState, c Canvas) error {
+	r, ok := c.(*recordingCanvas)
+	if !ok {
+		return nil
+	}
+	x, y := s.Position()
+	r.calls = append(r.calls, fmt.Sprintf("state %v %v %v", x+0, y+0,
+		s.Heading()+0))
+	return nil
+}
+
+func (n *recordStateTestInstruction) String() string {
+	return "Record state"
+}
+
+// Returns every call the turtle makes to a recordingCanvas, followed by its
+// final position and heading. Floating-point values are formatted so that
+// values differing in any bit produce different strings.
+func getCanvasCalls(t *testing.T, turtle *Turtle) []string {
+	c := &recordingCanvas{}
+	e := turtle.RenderToCanvas(c)
+	if e != nil {
+		t.Fatalf("Failed rendering turtle: %s", e)
+	}
+	return c.calls
+}
+
+// Returns the pixels drawn by the turtle on a canvas with fixed bounds, which,
+// unlike SaveTurtleAsPNG, works even if everything is drawn on a single line.
+func getFixedBoundsPixels(t *testing.T, turtle *Turtle) []uint8 {
+	c, e := NewRGBACanvas(100, 100, -10, -10, 10, 10, color.White)
+	if e != nil {
+		t.Fatalf("Failed creating canvas: %s", e)
+	}
+	e = turtle.RenderToCanvas(c)
+	if e != nil {
+		t.Fatalf("Failed rendering turtle: %s", e)
+	}
+	return c.pic.Pix
+}
+
+// Checks that optimizing the turtle doesn't change any of the coordinates it
+// draws at, its final position and heading, or its image. Returns the number
+// of instructions removed by optimizing.
+func checkOptimizedCalls(t *testing.T, turtle *Turtle, name string) int {
+	turtle.Add(&recordStateTestInstruction{})
+	before := getCanvasCalls(t, turtle)
+	beforePixels := getFixedBoundsPixels(t, turtle)
+	removed := turtle.Optimize()
+	after := getCanvasCalls(t, turtle)
+	if !bytes.Equal(beforePixels, getFixedBoundsPixels(t, turtle)) {
+		t.Errorf("%s: optimizing changed the rendered image", name)
+	}
+	if len(before) != len(after) {
+		t.Errorf("%s: optimizing changed the number of canvas calls from "+
+			"%d to %d:\n%s\n%s", name, len(before), len(after),
+			strings.Join(before, "\n"), strings.Join(after, "\n"))
+		return removed
+	}
+	for i := range before {
+		if before[i] != after[i] {
+			t.Errorf("%s: optimizing changed canvas call %d from %q to %q",
+				name, i, before[i], after[i])
+			return removed
+		}
+	}
+	return removed
+}
+
+func TestOptimizeKeepsCoordinates(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t *Turtle)
+	}{
+		{"Turn by 360", func(t *Turtle) {
+			t.Turn(0.1)
+			t.Turn(360)
+			t.MoveForward(1)
+		}},
+		{"Consecutive turns", func(t *Turtle) {
+			t.Turn(0.1)
+			t.Turn(0.2)
+			t.MoveForward(1)
+		}},
+		{"Turns cancelling out", func(t *Turtle) {
+			t.SetHeading(0.1)
+			t.Turn(300)
+			t.Turn(-300)
+			t.MoveForward(1)
+		}},
+		{"Zero turn at the start", func(t *Turtle) {
+			t.Turn(0)
+			t.MoveForward(1)
+		}},
+		{"Pen-up moves", func(t *Turtle) {
+			t.Turn(30)
+			t.PenUp()
+			t.MoveForward(0.1)
+			t.MoveForward(0.2)
+			t.PenDown()
+			t.MoveForward(1)
+		}},
+	}
+	for _, test := range tests {
+		// Start at a heading that a zero-degree turn would normalize.
+		turtle := NewTurtleWithOptions(TurtleOptions{Heading: 450.1})
+		test.build(turtle)
+		checkOptimizedCalls(t, turtle, test.name)
+	}
+}
+
+// Adds a random instruction to the turtle, favoring instructions that the
+// optimizer may remove or merge.
+func addRandomOptimizerTestInstruction(t *Turtle, r *rand.Rand) {
+	angles := []float64{0, 0.1, 0.2, 45, -45, 90, -90, 180, 300, -300, 360,
+		-360, 720, 1e-9}
+	distances := []float64{0, 0, 0.1, 0.2, 1, -1}
+	switch r.Intn(10) {
+	case 0, 1, 2:
+		t.Turn(angles[r.Intn(len(angles))] * float64(r.Intn(3)-1))
+	case 3, 4:
+		t.MoveForward(distances[r.Intn(len(distances))])
+	case 5:
+		t.SetHeading(angles[r.Intn(len(angles))])
+	case 6:
+		t.MoveArc(1, angles[r.Intn(len(angles))])
+	case 7:
+		if r.Intn(2) == 0 {
+			t.PenUp()
+		} else {
+			t.PenDown()
+		}
+	case 8:
+		t.SetStyle(GetColorStyle(color.Gray{uint8(r.Intn(2) * 0x80)}))
+	case 9:
+		t.PushPosition()
+		for i := r.Intn(4); i > 0; i-- {
+			addRandomOptimizerTestInstruction(t, r)
+		}
+		t.PopPosition()
+	}
+}
+
+func TestOptimizeRandomPrograms(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	totalRemoved := 0
+	for i := 0; i < 200; i++ {
+		turtle := NewTurtleWithOptions(TurtleOptions{
+			Heading: r.Float64() * 720,
+		})
+		for j := 0; j < 40; j++ {
+			addRandomOptimizerTestInstruction(turtle, r)
+		}
+		totalRemoved += checkOptimizedCalls(t, turtle,
+			fmt.Sprintf("Random program %d", i))
+	}
+	if totalRemoved == 0 {
+		t.Errorf("Optimizing the random programs didn't remove anything")
+	}
+}
+
+func TestOptimizeMergesExactTurns(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.SetHeading(0)
+	turtle.Turn(90)
+	turtle.Turn(90)
+	turtle.MoveForward(1)
+	turtle.Turn(90)
+	turtle.Turn(-90)
+	turtle.Turn(0)
+	turtle.Turn(360)
+	turtle.MoveForward(1)
+	removed := checkOptimizedCalls(t, turtle, "Exact turns")
+	if removed != 5 {
+		t.Errorf("Expected 5 turns to be removed, got %d", removed)
+	}
+}
+
+func TestOptimizeKeepsForwardMoves(t *testing.T) {
+	// Moves aren't merged, whether or not the pen is down, but zero-length
+	// moves with the pen up are removed.
+	turtle := NewTurtle()
+	turtle.MoveForward(1)
+	turtle.MoveForward(2)
+	turtle.PenUp()
+	turtle.MoveForward(1)
+	turtle.MoveForward(2)
+	turtle.MoveForward(0)
+	removed := checkOptimizedCalls(t, turtle, "Forward moves")
+	if removed != 1 {
+		t.Errorf("Expected 1 move to be removed, got %d", removed)
+	}
+}
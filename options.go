@@ -0,0 +1,144 @@
+package turtle_graphics
+
+// This file contains the options for configuring the units and conventions
+// used by a Turtle's methods.
+
+import (
+	"fmt"
+	"math"
+)
+
+// Specifies the unit in which angles are passed to a Turtle's methods.
+type AngleUnit int
+
+const (
+	// Angles are in degrees. This is the default.
+	Degrees AngleUnit = iota
+	// Angles are in radians.
+	Radians
+	// Angles are in gradians, with 400 gradians in a full circle.
+	Gradians
+	// Angles are in full turns, so 1 is a full circle.
+	Turns
+)
+
+func (u AngleUnit) String() string {
+	switch u {
+	case Degrees:
+		return "degrees"
+	case Radians:
+		return "radians"
+	case Gradians:
+		return "gradians"
+	case Turns:
+		return "turns"
+	}
+	return fmt.Sprintf("unknown angle unit %d", int(u))
+}
+
+// Returns the number of degrees in one of the given unit.
+func (u AngleUnit) degreesPerUnit() float64 {
+	switch u {
+	case Radians:
+		return 180.0 / math.Pi
+	case Gradians:
+		return 0.9
+	case Turns:
+		return 360.0
+	}
+	return 1.0
+}
+
+// Specifies the direction of a heading of 0, and the direction of positive
+// turns.
+type Orientation int
+
+const (
+	// A heading of 0 faces along the positive X axis, and positive turns are
+	// counter-clockwise, as in mathematics. This is the default.
+	StandardOrientation Orientation = iota
+	// A heading of 0 faces along the positive Y axis ("north"), and positive
+	// turns are clockwise, as in Logo and compass bearings.
+	LogoOrientation
+)
+
+func (o Orientation) String() string {
+	switch o {
+	case StandardOrientation:
+		return "standard"
+	case LogoOrientation:
+		return "logo"
+	}
+	return fmt.Sprintf("unknown orientation %d", int(o))
+}
+
+// Options that control the conventions used by a Turtle's methods. The zero
+// value uses degrees and the standard orientation, starting at the origin
+// facing along the positive X axis.
+type TurtleOptions struct {
+	// The unit of all angles passed to the Turtle's methods, including
+	// Heading.
+	AngleUnit AngleUnit
+	// The direction of a heading of 0 and of positive turns, including the
+	// direction of arcs.
+	Orientation Orientation
+	// The position at which the turtle starts, and to which Home returns.
+	X, Y float64
+	// The direction the turtle faces at the start, and after Home, using
+	// AngleUnit and Orientation.
+	Heading float64
+}
+
+// Returns an initialized Turtle instance with no instructions, which
+// interprets the angles passed to its methods according to the given
+// options. Angles are converted as each instruction is added, so the
+// conversion has no cost when rendering. TurtleState and
+// InstructionDescriptor always use degrees and the standard orientation.
+func NewTurtleWithOptions(options TurtleOptions) *Turtle {
+	t := NewTurtle()
+	t.options = options
+	t.start = turtlePosition{
+		x:     options.X,
+		y:     options.Y,
+		angle: t.absoluteHeading(options.Heading),
+		penUp: false,
+	}
+	return t
+}
+
+// Returns the options the turtle was created with. A turtle created using
+// NewTurtle uses the zero value.
+func (t *Turtle) Options() TurtleOptions {
+	return t.options
+}
+
+// Converts an angle passed to one of the turtle's methods to a turn in
+// degrees, counter-clockwise.
+func (t *Turtle) relativeAngle(angle float64) float64 {
+	degrees := angle * t.options.AngleUnit.degreesPerUnit()
+	if t.options.Orientation == LogoOrientation {
+		return -degrees
+	}
+	return degrees
+}
+
+// Converts a heading passed to one of the turtle's methods to a heading in
+// degrees, counter-clockwise from the positive X axis.
+func (t *Turtle) absoluteHeading(heading float64) float64 {
+	degrees := heading * t.options.AngleUnit.degreesPerUnit()
+	if t.options.Orientation == LogoOrientation {
+		return 90.0 - degrees
+	}
+	return degrees
+}
+
+// Converts a distance to one side of the turtle passed to one of its methods,
+// such as an arc's radius, to a distance to the turtle's left. The distance is
+// towards the side that positive turns turn to, so it's to the turtle's right
+// if the turtle uses LogoOrientation.
+func (t *Turtle) leftDistance(distance float64) float64 {
+	if t.options.Orientation == LogoOrientation {
+		return -distance
+	}
+	return distance
+}
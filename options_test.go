@@ -0,0 +1,48 @@
+package turtle_graphics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLogoOrientationCurves(t *testing.T) {
+	logo := TurtleOptions{Orientation: LogoOrientation}
+	// Each turtle starts facing up, and turns to its right, ending at (1, 1)
+	// facing along the positive X axis.
+	arc := NewTurtleWithOptions(logo)
+	arc.MoveArc(1, 90)
+	quadratic := NewTurtleWithOptions(logo)
+	quadratic.QuadraticBezier(1, 0, 1, 1)
+	cubic := NewTurtleWithOptions(logo)
+	cubic.CubicBezier(0.5, 0, 1, 0.5, 1, 1)
+	for i, turtle := range []*Turtle{arc, quadratic, cubic} {
+		_, state := getFinalState(t, turtle)
+		if (math.Abs(state.x-1) > 1e-9) || (math.Abs(state.y-1) > 1e-9) ||
+			(headingDifference(state.heading, 0) > 1e-9) {
+			t.Errorf("Expected curve %d to end at (1, 1) with heading 0, "+
+				"got (%f, %f) with heading %f", i, state.x, state.y,
+				state.heading)
+		}
+	}
+
+	// Descriptors use the default options, so distances to the right are
+	// negated.
+	expected := [][]float64{{-1, -90}, {1, -0, 1, -1},
+		{0.5, -0, 1, -0.5, 1, -1}}
+	for i, turtle := range []*Turtle{arc, quadratic, cubic} {
+		it := turtle.Instructions()
+		it.Next()
+		operands := it.Descriptor().Operands
+		if len(operands) != len(expected[i]) {
+			t.Errorf("Expected %d operands for curve %d, got %d",
+				len(expected[i]), i, len(operands))
+			continue
+		}
+		for j, v := range operands {
+			if v != expected[i][j] {
+				t.Errorf("Expected operand %d of curve %d to be %f, got %f",
+					j, i, expected[i][j], v)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package turtle_graphics
+
+// This file contains the code for converting the lines and arcs drawn by a
+// turtle into a list of polylines.
+
+import (
+	"fmt"
+	"math"
+)
+
+// A point, in canvas units.
+type Point struct {
+	X, Y float64
+}
+
+// A connected sequence of line segments drawn by a turtle, all using the same
+// stroke style.
+type Path struct {
+	// The vertices of the path, in order. Always contains at least two
+	// points.
+	Points []Point
+	// The style that was active when the path was drawn. nil if the path was
+	// drawn before any SetStyle instruction.
+	Style StrokeStyle
+}
+
+// Returns the points along an arc, as described by the arguments to
+// Canvas.DrawArc, such that no point on the arc is farther than tolerance from
+// the line segments connecting the points. The returned points exclude the
+// starting point, (x, y). The final point is computed in the same way that a
+// turtle computes its position after moving along an arc.
+func flattenArc(x, y, angle, radius, degrees, tolerance float64) []Point {
+	centerX, centerY := moveDegrees(x, y, angle+90.0, radius)
+	absRadius := math.Abs(radius)
+	// Limit the angle between points so that the distance between the arc
+	// and each segment (the sagitta) is within the tolerance.
+	stepDegrees := 45.0
+	if tolerance < absRadius {
+		maxStep := 2 * math.Acos(1-tolerance/absRadius) * 180.0 / math.Pi
+		if maxStep < stepDegrees {
+			stepDegrees = maxStep
+		}
+	}
+	count := int(math.Ceil(math.Abs(degrees) / stepDegrees))
+	if count < 1 {
+		count = 1
+	}
+	toReturn := make([]Point, count)
+	for i := 1; i < count; i++ {
+		a := degrees * float64(i) / float64(count)
+		px, py := moveDegrees(centerX, centerY, a+(angle-90.0), radius)
+		toReturn[i-1] = Point{px, py}
+	}
+	px, py := moveDegrees(centerX, centerY, degrees+(angle-90.0), radius)
+	toReturn[count-1] = Point{px, py}
+	return toReturn
+}
+
+// Returns cubic Bezier curves approximating an arc, as described by the
+// arguments to Canvas.DrawArc. Each curve covers at most 90 degrees of the
+// arc, and is given as its start point, two control points and end point.
+// Affine transformations can be applied to the curves by transforming these
+// points, so this can be used to draw arcs that are no longer circular.
+func arcToBeziers(x, y, angle, radius, degrees float64) [][4]Point {
+	centerX, centerY := moveDegrees(x, y, angle+90.0, radius)
+	count := int(math.Ceil(math.Abs(degrees) / 90.0))
+	if count < 1 {
+		count = 1
+	}
+	step := degrees / float64(count)
+	// The distance from each end point to its control point, as a fraction
+	// of the radius, for the curve to best match the arc.
+	k := 4.0 / 3.0 * math.Tan(step*math.Pi/720.0) * radius
+	toReturn := make([][4]Point, count)
+	start := Point{x, y}
+	for i := range toReturn {
+		a0 := angle - 90.0 + step*float64(i)
+		a1 := a0 + step
+		endX, endY := moveDegrees(centerX, centerY, a1, radius)
+		if i == (count - 1) {
+			// Match the turtle's final position exactly.
+			endX, endY = moveDegrees(centerX, centerY, degrees+(angle-90.0),
+				radius)
+		}
+		c0X, c0Y := moveDegrees(start.X, start.Y, a0+90.0, k)
+		c1X, c1Y := moveDegrees(endX, endY, a1-90.0, k)
+		toReturn[i] = [4]Point{start, {c0X, c0Y}, {c1X, c1Y}, {endX, endY}}
+		start = Point{endX, endY}
+	}
+	return toReturn
+}
+
+// Implements the Canvas interface, collecting the lines and arcs that are
+// drawn into a list of paths.
+type pathCanvas struct {
+	tolerance float64
+	style     StrokeStyle
+	// Set when the style changes, so the next segment starts a new path.
+	styleChanged bool
+	paths        []Path
+}
+
+func (c *pathCanvas) SetStyle(s StrokeStyle) error {
+	c.style = s
+	c.styleChanged = true
+	return nil
+}
+
+// Adds a line segment from a to b, extending the current path if it ends at a.
+func (c *pathCanvas) addSegment(a, b Point) {
+	if !c.styleChanged && (len(c.paths) != 0) {
+		current := &(c.paths[len(c.paths)-1])
+		if current.Points[len(current.Points)-1] == a {
+			current.Points = append(current.Points, b)
+			return
+		}
+	}
+	c.styleChanged = false
+	c.paths = append(c.paths, Path{
+		Points: []Point{a, b},
+		Style:  c.style,
+	})
+}
+
+func (c *pathCanvas) DrawLine(x, y, angle, length float64) error {
+	endX, endY := moveDegrees(x, y, angle, length)
+	c.addSegment(Point{x, y}, Point{endX, endY})
+	return nil
+}
+
+func (c *pathCanvas) DrawArc(x, y, angle, radius, degrees float64) error {
+	start := Point{x, y}
+	for _, p := range flattenArc(x, y, angle, radius, degrees, c.tolerance) {
+		c.addSegment(start, p)
+		start = p
+	}
+	return nil
+}
+
+// Implements the BezierCanvas interface, so curves are approximated using the
+// path tolerance.
+func (c *pathCanvas) DrawBezier(p0, p1, p2, p3 Point) error {
+	start := p0
+	for _, p := range flattenCubic(nil, p0, p1, p2, p3, c.tolerance, 0) {
+		c.addSegment(start, p)
+		start = p
+	}
+	return nil
+}
+
+// Returns the lines the turtle draws as a list of polylines, by rendering the
+// turtle in the same way as RenderToCanvas. Consecutive segments are joined
+// into a single path if each starts where the previous one ended and the
+// style didn't change between them. Arcs and curves are approximated by line
+// segments that are no farther than tolerance from the true curve, so
+// tolerance must be positive.
+func (t *Turtle) Paths(tolerance float64) ([]Path, error) {
+	if !(tolerance > 0) {
+		return nil, fmt.Errorf("The path tolerance must be positive, got %f",
+			tolerance)
+	}
+	c := &pathCanvas{
+		tolerance: tolerance,
+		paths:     make([]Path, 0, 64),
+	}
+	e := t.RenderToCanvas(c)
+	if e != nil {
+		return nil, fmt.Errorf("Failed tracing the turtle's paths: %w", e)
+	}
+	return c.paths, nil
+}
@@ -0,0 +1,141 @@
+package turtle_graphics
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// Returns the turtle's paths, failing the test on error.
+func getTestPaths(t *testing.T, turtle *Turtle, tolerance float64) []Path {
+	paths, e := turtle.Paths(tolerance)
+	if e != nil {
+		t.Fatalf("Failed getting paths: %s", e)
+	}
+	return paths
+}
+
+// Checks that the path's points are within 1e-9 of the expected points.
+func checkPathPoints(t *testing.T, path Path, expected []Point) {
+	if len(path.Points) != len(expected) {
+		t.Errorf("Expected %d points in the path, got %d: %v", len(expected),
+			len(path.Points), path.Points)
+		return
+	}
+	for i, p := range path.Points {
+		if (math.Abs(p.X-expected[i].X) > 1e-9) ||
+			(math.Abs(p.Y-expected[i].Y) > 1e-9) {
+			t.Errorf("Expected point %d of the path to be %v, got %v", i,
+				expected[i], p)
+		}
+	}
+}
+
+func TestPathsPenUp(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.MoveForward(1)
+	turtle.Turn(90)
+	turtle.MoveForward(1)
+	turtle.PenUp()
+	turtle.MoveForward(1)
+	turtle.PenDown()
+	turtle.MoveForward(1)
+	// Moving back to where the last line started doesn't join the paths.
+	turtle.PenUp()
+	turtle.GoTo(1, 2)
+	turtle.PenDown()
+	turtle.MoveForward(1)
+	paths := getTestPaths(t, turtle, 0.01)
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 paths, got %d: %v", len(paths), paths)
+	}
+	checkPathPoints(t, paths[0], []Point{{0, 0}, {1, 0}, {1, 1}})
+	checkPathPoints(t, paths[1], []Point{{1, 2}, {1, 3}})
+	checkPathPoints(t, paths[2], []Point{{1, 2}, {1, 3}})
+}
+
+func TestPathsArcs(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.MoveArc(1, 180)
+	turtle.MoveForward(1)
+	previousCount := 0
+	for _, tolerance := range []float64{0.1, 0.01, 0.001} {
+		paths := getTestPaths(t, turtle, tolerance)
+		// The arc ends exactly where the turtle does, so the following line
+		// continues the same path.
+		if len(paths) != 1 {
+			t.Fatalf("Expected 1 path, got %d: %v", len(paths), paths)
+		}
+		points := paths[0].Points
+		if len(points) <= previousCount {
+			t.Errorf("A tolerance of %f used %d points, but a larger one "+
+				"used %d", tolerance, len(points), previousCount)
+		}
+		previousCount = len(points)
+		// The arc is centered on (0, 1), and followed by a line from (0, 2)
+		// to (-1, 2).
+		center := Point{0, 1}
+		arc := points[0 : len(points)-1]
+		for i, p := range arc {
+			d := math.Hypot(p.X-center.X, p.Y-center.Y)
+			if math.Abs(d-1) > 1e-9 {
+				t.Errorf("Point %d, %v, isn't on the arc", i, p)
+			}
+			if i == 0 {
+				continue
+			}
+			// The middle of each segment must be within the tolerance.
+			mid := Point{(p.X + arc[i-1].X) / 2, (p.Y + arc[i-1].Y) / 2}
+			d = math.Hypot(mid.X-center.X, mid.Y-center.Y)
+			if (1 - d) > (tolerance + 1e-12) {
+				t.Errorf("Segment %d is %f from the arc, which is farther "+
+					"than the tolerance of %f", i, 1-d, tolerance)
+			}
+		}
+		checkPathPoints(t, Path{Points: []Point{arc[0], arc[len(arc)-1],
+			points[len(points)-1]}}, []Point{{0, 0}, {0, 2}, {-1, 2}})
+	}
+}
+
+func TestPathsStyles(t *testing.T) {
+	red := GetColorStyle(color.NRGBA{0xff, 0, 0, 0xff})
+	blue := GetColorStyle(color.NRGBA{0, 0, 0xff, 0xff})
+	turtle := NewTurtle()
+	turtle.MoveForward(1)
+	turtle.SetStyle(red)
+	turtle.MoveForward(1)
+	turtle.MoveArc(1, 90)
+	turtle.PushPosition()
+	turtle.SetStyle(blue)
+	turtle.MoveForward(1)
+	turtle.PopPosition()
+	turtle.MoveForward(1)
+	paths := getTestPaths(t, turtle, 0.01)
+	if len(paths) != 4 {
+		t.Fatalf("Expected 4 paths, got %d: %v", len(paths), paths)
+	}
+	// A style change starts a new path, even if the line is connected.
+	expected := []StrokeStyle{nil, red, blue, red}
+	for i, p := range paths {
+		if p.Style != expected[i] {
+			t.Errorf("Expected path %d to have style %v, got %v", i,
+				expected[i], p.Style)
+		}
+	}
+	if paths[0].Points[1] != paths[1].Points[0] {
+		t.Errorf("The second path didn't start where the first ended")
+	}
+}
+
+func TestPathsErrors(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.MoveArc(1, 90)
+	for _, tolerance := range []float64{0, -1, math.NaN()} {
+		_, e := turtle.Paths(tolerance)
+		if e == nil {
+			t.Errorf("Didn't get an error using a tolerance of %f", tolerance)
+			continue
+		}
+		t.Logf("Got expected error: %s", e)
+	}
+}
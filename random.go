@@ -0,0 +1,127 @@
+package turtle_graphics
+
+// This file contains instructions that make random choices. The choices are
+// recorded as part of the turtle's program, but are only made during
+// rendering, using a random number generator seeded from the Turtle's seed.
+// So, rendering the same Turtle multiple times, e.g. to a DummyCanvas and then
+// to an RGBACanvas, always produces the same drawing.
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// The maximum number of subprograms that may be nested while rendering. Deeper
+// nesting usually means that a subprogram contains itself.
+const maxSubprogramDepth = 1000
+
+// Carries out the given instructions as part of a larger program, using the
+// same state and canvas.
+func runSubprogram(instructions *instructionList, s *TurtleState,
+	c Canvas) error {
+	if s.subprogramDepth >= maxSubprogramDepth {
+		return fmt.Errorf("Subprograms nested more than %d deep",
+			maxSubprogramDepth)
+	}
+	s.subprogramDepth++
+	defer func() { s.subprogramDepth-- }()
+	return instructions.run(s, c)
+}
+
+// Returns a random number in the range [min, max).
+func randomInRange(s *TurtleState, min, max float64) float64 {
+	return min + s.random.Float64()*(max-min)
+}
+
+// An instruction telling the turtle to turn by a random number of degrees.
+type turnRandomInstruction struct {
+	min, max float64
+}
+
+func (n *turnRandomInstruction) String() string {
+	return fmt.Sprintf("Turn by between %f and %f degrees", n.min, n.max)
+}
+
+func (n *turnRandomInstruction) Apply(s *TurtleState, c Canvas) error {
+	turn := turnInstruction{
+		degrees: randomInRange(s, n.min, n.max),
+	}
+	return turn.Apply(s, c)
+}
+
+// An instruction telling the turtle to move forward by a random distance.
+type moveForwardRandomInstruction struct {
+	min, max float64
+}
+
+func (n *moveForwardRandomInstruction) String() string {
+	return fmt.Sprintf("Move forward by between %f and %f units", n.min,
+		n.max)
+}
+
+func (n *moveForwardRandomInstruction) Apply(s *TurtleState, c Canvas) error {
+	move := moveForwardInstruction{
+		distance: randomInRange(s, n.min, n.max),
+	}
+	return move.Apply(s, c)
+}
+
+// An instruction that carries out a subprogram with a given probability.
+type chanceInstruction struct {
+	probability float64
+	subprogram  *Turtle
+}
+
+func (n *chanceInstruction) String() string {
+	return fmt.Sprintf("With probability %f, run %d instructions",
+		n.probability, n.subprogram.Len())
+}
+
+func (n *chanceInstruction) Apply(s *TurtleState, c Canvas) error {
+	if s.random.Float64() >= n.probability {
+		return nil
+	}
+	if n.subprogram == nil {
+		return ErrNilSubprogram
+	}
+	return runSubprogram(&(n.subprogram.instructions), s, c)
+}
+
+// Sets the seed used to initialize the random number generator at the start
+// of each rendering. Turtles use a seed of 0 by default. If the turtle is
+// streaming instructions from an InstructionSource, the random number
+// generator is reseeded immediately, affecting only later instructions.
+func (t *Turtle) SetSeed(seed int64) {
+	t.seed = seed
+	if t.stream != nil {
+		t.stream.state.random = rand.New(rand.NewSource(seed))
+	}
+}
+
+// Returns the seed used to initialize the turtle's random number generator.
+func (t *Turtle) Seed() int64 {
+	return t.seed
+}
+
+// Adds an instruction to turn by a random number of degrees, chosen uniformly
+// from the range [min, max) each time the turtle is rendered. Like Turn, the
+// angles are in the turtle's AngleUnit and Orientation.
+func (t *Turtle) TurnRandom(min, max float64) {
+	t.addOp(opTurnRandom, nil, t.relativeAngle(min), t.relativeAngle(max))
+}
+
+// Adds an instruction to move forward by a random distance, chosen uniformly
+// from the range [min, max) each time the turtle is rendered.
+func (t *Turtle) MoveForwardRandom(min, max float64) {
+	t.addOp(opMoveForwardRandom, nil, min, max)
+}
+
+// Adds an instruction to carry out the subprogram's instructions with the
+// given probability, between 0 and 1, as if they were this turtle's own. The
+// subprogram isn't copied, and a nil subprogram adds nothing.
+func (t *Turtle) Chance(probability float64, subprogram *Turtle) {
+	if subprogram == nil {
+		return
+	}
+	t.addOp(opChance, subprogram, probability)
+}
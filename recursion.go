@@ -0,0 +1,111 @@
+package turtle_graphics
+
+// This file contains instructions for drawing self-similar shapes, such as
+// fractals, using a procedure that calls itself. The recursion is carried out
+// while rendering, so only the procedure's instructions are stored, no matter
+// how deep the drawing goes.
+
+import (
+	"fmt"
+)
+
+// A procedure carried out by CallRecursive: a turtle whose Recurse
+// instructions carry it out again, and the turtle drawn in place of the
+// procedure once the recursion reaches its maximum depth.
+type procedure struct {
+	body *Turtle
+	base *Turtle
+}
+
+// Identifies the procedure being carried out by a turtle, if any, and how many
+// more times it may recurse.
+type recursionLevel struct {
+	// The procedure carried out by the most recent CallRecursive. nil if the
+	// turtle isn't carrying out a procedure.
+	procedure *procedure
+	// The number of levels remaining. Recurse draws the base turtle if this
+	// is 0.
+	depth int
+}
+
+// Carries out the procedure with the given number of levels remaining,
+// relative to the turtle's position, and then moves the turtle to where the
+// procedure ended up. Draws the procedure's base turtle instead if no levels
+// remain.
+func (s *TurtleState) callProcedure(c Canvas, p *procedure, depth int,
+	scale float64) error {
+	if depth <= 0 {
+		if p.base == nil {
+			return nil
+		}
+		return s.callTurtle(c, p.base, scale, recursionLevel{})
+	}
+	level := recursionLevel{
+		procedure: p,
+		depth:     depth,
+	}
+	return s.callTurtle(c, p.body, scale, level)
+}
+
+// An instruction that starts carrying out a procedure recursively.
+type callRecursiveInstruction struct {
+	procedure *procedure
+	depth     int
+	scale     float64
+}
+
+func (n *callRecursiveInstruction) String() string {
+	return fmt.Sprintf("Call a procedure of %d instructions to depth %d, "+
+		"scaled by %f", n.procedure.body.Len(), n.depth,
+		n.scale)
+}
+
+func (n *callRecursiveInstruction) Apply(s *TurtleState, c Canvas) error {
+	return s.callProcedure(c, n.procedure, n.depth,
+		n.scale*s.position.stepScale)
+}
+
+// An instruction that carries out the current procedure again, one level
+// deeper.
+type recurseInstruction struct {
+	scale float64
+}
+
+func (n *recurseInstruction) String() string {
+	return fmt.Sprintf("Recurse, scaled by %f", n.scale)
+}
+
+func (n *recurseInstruction) Apply(s *TurtleState, c Canvas) error {
+	level := s.recursion
+	if level.procedure == nil {
+		return ErrNoProcedure
+	}
+	return s.callProcedure(c, level.procedure, level.depth-1,
+		n.scale*s.position.stepScale)
+}
+
+// Adds an instruction to draw the body like Call, where each Recurse in the
+// body draws it again one level deeper, until depth levels are reached and
+// Recurse draws the base turtle instead. The base may be nil to draw nothing
+// at the deepest level, but a nil body means there's nothing to add.
+func (t *Turtle) CallRecursive(body, base *Turtle, depth int,
+	scale float64) {
+	if body == nil {
+		return
+	}
+	p := &procedure{
+		body: body,
+		base: base,
+	}
+	t.addOp(opCallRecursive, p, float64(depth), scale)
+}
+
+// Adds an instruction to carry out the current procedure again, one level
+// deeper, or its base turtle if the procedure has reached the depth passed to
+// CallRecursive. The distances drawn are multiplied by the given scale and
+// this turtle's step scale. May only be used in a procedure carried out by
+// CallRecursive, or in the subprograms it uses; rendering fails with
+// ErrNoProcedure otherwise.
+func (t *Turtle) Recurse(scale float64) {
+	t.addOp(opRecurse, nil, scale)
+}
@@ -4,11 +4,13 @@ package turtle_graphics
 // interface, that can actually be used for rendering the turtle graphics.
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"io"
+	"math"
 )
 
 // Keeps track of an RGBA image, along with the canvas boundaries needed to
@@ -17,6 +19,9 @@ import (
 type RGBACanvas struct {
 	// The style with which to draw strokes.
 	style StrokeStyle
+	// The width of strokes, if the style is a WidthStrokeStyle. Lines that
+	// aren't wider than a pixel are drawn one pixel wide.
+	lineWidth float64
 	// The underlying RGBA image.
 	pic *image.RGBA
 	// The width and height of the image, in pixels.
@@ -73,6 +78,7 @@ func NewRGBACanvas(pixelsWide, pixelsTall int, minX, minY, maxX,
 
 	toReturn := &RGBACanvas{
 		style:      GetColorStyle(color.Black),
+		lineWidth:  0,
 		pic:        pic,
 		pixelsWide: pixelsWide,
 		pixelsTall: pixelsTall,
@@ -88,9 +94,93 @@ func NewRGBACanvas(pixelsWide, pixelsTall int, minX, minY, maxX,
 
 func (c *RGBACanvas) SetStyle(s StrokeStyle) error {
 	c.style = s
+	c.lineWidth = 0
+	if widthStyle, ok := s.(WidthStrokeStyle); ok {
+		c.lineWidth = widthStyle.GetWidth()
+	}
 	return nil
 }
 
+// Returns true if lines should be drawn wider than a single pixel.
+func (c *RGBACanvas) drawsWideLines() bool {
+	return (c.lineWidth > c.dX) || (c.lineWidth > c.dY)
+}
+
+// Updates lo and hi to include the range of x coordinates at which the
+// horizontal line at y intersects the circle with the given center and
+// radius.
+func circleSpan(center Point, radius, y float64, lo, hi *float64) {
+	dy := y - center.Y
+	if math.Abs(dy) > radius {
+		return
+	}
+	halfWidth := math.Sqrt(radius*radius - dy*dy)
+	*lo = math.Min(*lo, center.X-halfWidth)
+	*hi = math.Max(*hi, center.X+halfWidth)
+}
+
+// Fills the pixels whose centers are within radius of the line segment from
+// a to b, producing a wide line with rounded ends. The rounded ends also
+// cover the joints between consecutive segments.
+func (c *RGBACanvas) fillWideSegment(a, b Point, radius float64,
+	fillColor color.Color) {
+	// The corners of the rectangle covering the segment, excluding its
+	// rounded ends.
+	var corners [4]Point
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length > 0 {
+		normalX := -(b.Y - a.Y) / length * radius
+		normalY := (b.X - a.X) / length * radius
+		corners = [4]Point{
+			{a.X + normalX, a.Y + normalY},
+			{b.X + normalX, b.Y + normalY},
+			{b.X - normalX, b.Y - normalY},
+			{a.X - normalX, a.Y - normalY},
+		}
+	}
+	// Rows are flipped, so the bottom of the segment is in the highest row.
+	_, bottom := c.PointToPixel(0, math.Min(a.Y, b.Y)-radius)
+	_, top := c.PointToPixel(0, math.Max(a.Y, b.Y)+radius)
+	top = clampPixel(top, c.pixelsTall)
+	bottom = clampPixel(bottom, c.pixelsTall)
+	yMax := c.pixelsTall - 1
+	for row := top; row <= bottom; row++ {
+		y := c.minY + (float64(yMax-row)+0.5)*c.dY
+		// The region is convex, so its intersection with the row is a single
+		// range of x coordinates.
+		lo := math.Inf(1)
+		hi := math.Inf(-1)
+		circleSpan(a, radius, y, &lo, &hi)
+		circleSpan(b, radius, y, &lo, &hi)
+		for i := 0; (length > 0) && (i < len(corners)); i++ {
+			p := corners[i]
+			q := corners[(i+1)%len(corners)]
+			if (y < math.Min(p.Y, q.Y)) || (y > math.Max(p.Y, q.Y)) ||
+				(p.Y == q.Y) {
+				continue
+			}
+			x := p.X + (y-p.Y)*(q.X-p.X)/(q.Y-p.Y)
+			lo = math.Min(lo, x)
+			hi = math.Max(hi, x)
+		}
+		if lo > hi {
+			continue
+		}
+		// Fill the pixels with centers in [lo, hi].
+		start := math.Ceil((lo-c.minX)/c.dX - 0.5)
+		end := math.Floor((hi-c.minX)/c.dX - 0.5)
+		if start < 0 {
+			start = 0
+		}
+		if end >= float64(c.pixelsWide) {
+			end = float64(c.pixelsWide - 1)
+		}
+		for x := int(start); x <= int(end); x++ {
+			c.pic.Set(x, row, fillColor)
+		}
+	}
+}
+
 func abs(x int) int {
 	if x >= 0 {
 		return x
@@ -148,14 +238,36 @@ func (c *RGBACanvas) PointToPixel(x, y float64) (int, int) {
 }
 
 func (c *RGBACanvas) DrawLine(x, y, angle, length float64) error {
-	x0, y0 := c.PointToPixel(x, y)
 	newX, newY := moveDegrees(x, y, angle, length)
+	if c.drawsWideLines() {
+		c.fillWideSegment(Point{x, y}, Point{newX, newY}, c.lineWidth/2,
+			c.style.GetColor())
+		return nil
+	}
+	x0, y0 := c.PointToPixel(x, y)
 	x1, y1 := c.PointToPixel(newX, newY)
 	drawLine(x0, y0, x1, y1, c.pic, c.style)
 	return nil
 }
 
+// Draws an arc wider than a single pixel, by approximating it using wide line
+// segments.
+func (c *RGBACanvas) drawWideArc(x, y, angle, radius, degrees float64) {
+	degrees = math.Max(math.Min(degrees, 360), -360)
+	tolerance := math.Min(c.dX, c.dY) / 2
+	previous := Point{x, y}
+	arcColor := c.style.GetColor()
+	for _, p := range flattenArc(x, y, angle, radius, degrees, tolerance) {
+		c.fillWideSegment(previous, p, c.lineWidth/2, arcColor)
+		previous = p
+	}
+}
+
 func (c *RGBACanvas) DrawArc(x, y, angle, radius, degrees float64) error {
+	if c.drawsWideLines() {
+		c.drawWideArc(x, y, angle, radius, degrees)
+		return nil
+	}
 	centerX, centerY := moveDegrees(x, y, angle+90.0, radius)
 	// Make degrees positive, and clamp to 360 (when rasterizing, we don't care
 	// if a turtle goes around multiple times, or in what direction).
@@ -207,20 +319,211 @@ func (c *RGBACanvas) DrawArc(x, y, angle, radius, degrees float64) error {
 	return nil
 }
 
+// Returns i, clamped to the range [0, max).
+func clampPixel(i, max int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= max {
+		return max - 1
+	}
+	return i
+}
+
+// Implements the DotCanvas interface. Fills each pixel whose center is inside
+// the circle, or only the pixel containing the center if the dot is too small
+// to cover any pixel centers.
+func (c *RGBACanvas) DrawDot(x, y, diameter float64) error {
+	radius := math.Abs(diameter) / 2
+	dotColor := c.style.GetColor()
+	// Only check the pixels in the dot's bounding box. Rows are flipped, so
+	// the bottom of the dot is in the highest row.
+	left, bottom := c.PointToPixel(x-radius, y-radius)
+	right, top := c.PointToPixel(x+radius, y+radius)
+	left = clampPixel(left, c.pixelsWide)
+	right = clampPixel(right, c.pixelsWide)
+	top = clampPixel(top, c.pixelsTall)
+	bottom = clampPixel(bottom, c.pixelsTall)
+	yMax := c.pixelsTall - 1
+	filled := false
+	for row := top; row <= bottom; row++ {
+		pixelY := c.minY + (float64(yMax-row)+0.5)*c.dY
+		for col := left; col <= right; col++ {
+			pixelX := c.minX + (float64(col)+0.5)*c.dX
+			if math.Hypot(pixelX-x, pixelY-y) > radius {
+				continue
+			}
+			c.pic.Set(col, row, dotColor)
+			filled = true
+		}
+	}
+	if !filled {
+		col, row := c.PointToPixel(x, y)
+		c.pic.Set(col, row, dotColor)
+	}
+	return nil
+}
+
+// An intersection between a horizontal scanline and a polygon's edge. The
+// winding is 1 if the edge goes upward and -1 if it goes downward.
+type scanlineCrossing struct {
+	x       float64
+	winding int
+}
+
+// Sorts scanline crossings by x coordinate. Uses insertion sort, since there
+// are usually very few crossings per scanline.
+func sortCrossings(crossings []scanlineCrossing) {
+	for i := 1; i < len(crossings); i++ {
+		for j := i; (j > 0) && (crossings[j].x < crossings[j-1].x); j-- {
+			crossings[j], crossings[j-1] = crossings[j-1], crossings[j]
+		}
+	}
+}
+
+// Implements the FillCanvas interface. Uses a scanline algorithm, filling
+// each pixel whose center is inside the polygon according to the fill rule.
+func (c *RGBACanvas) FillPolygon(points []Point, style StrokeStyle,
+	rule FillRule) error {
+	if len(points) < 3 {
+		return nil
+	}
+	fillColor := style.GetColor()
+	crossings := make([]scanlineCrossing, 0, 16)
+	yMax := c.pixelsTall - 1
+	for row := 0; row < c.pixelsTall; row++ {
+		// The canvas y coordinate of the center of this row of pixels.
+		y := c.minY + (float64(yMax-row)+0.5)*c.dY
+		crossings = crossings[0:0]
+		for i := range points {
+			a := points[i]
+			b := points[(i+1)%len(points)]
+			// Count an edge if the scanline is within [min y, max y) so
+			// vertices shared by two edges aren't counted twice.
+			winding := 1
+			if a.Y > b.Y {
+				a, b = b, a
+				winding = -1
+			}
+			if (y < a.Y) || (y >= b.Y) {
+				continue
+			}
+			x := a.X + (y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			crossings = append(crossings, scanlineCrossing{
+				x:       x,
+				winding: winding,
+			})
+		}
+		sortCrossings(crossings)
+		windingNumber := 0
+		for i := 0; i < len(crossings)-1; i++ {
+			windingNumber += crossings[i].winding
+			inside := windingNumber != 0
+			if rule == EvenOddFill {
+				inside = (i % 2) == 0
+			}
+			if !inside {
+				continue
+			}
+			// Fill the pixels with centers in [start, end).
+			start := math.Ceil((crossings[i].x-c.minX)/c.dX - 0.5)
+			end := math.Ceil((crossings[i+1].x-c.minX)/c.dX - 0.5)
+			if start < 0 {
+				start = 0
+			}
+			if end > float64(c.pixelsWide) {
+				end = float64(c.pixelsWide)
+			}
+			for x := int(start); x < int(end); x++ {
+				c.pic.Set(x, row, fillColor)
+			}
+		}
+	}
+	return nil
+}
+
 // A wrapper function that goes through the entire process of rendering a
 // turtle to a PNG-format file. The PNG file is written to the given out
 // stream. Requires the height of the image, in pixels. The width is
 // automatically calculated to maintain a square aspect ratio.
 func SaveTurtleAsPNG(t *Turtle, pixelsTall int, out io.Writer) error {
+	return SaveTurtleAsPNGContext(context.Background(), t, pixelsTall, out,
+		nil)
+}
+
+// Like SaveTurtleAsPNG, but uses RenderToCanvasContext to render the turtle,
+// so rendering can be canceled using ctx. The turtle is rendered twice: once
+// to compute the image's bounds and once to draw it. So, if opts contains a
+// Progress function, the total it receives is twice the number of
+// instructions, and the count of instructions done continues increasing
+// during the second rendering. The opts may be nil to use default options.
+func SaveTurtleAsPNGContext(ctx context.Context, t *Turtle, pixelsTall int,
+	out io.Writer, opts *RenderOptions) error {
+	return renderPNG(pixelsTall, out, opts,
+		func(c Canvas, passOptions *RenderOptions) error {
+			return t.RenderToCanvasContext(ctx, c, passOptions)
+		})
+}
+
+// Like SaveTurtleAsPNG, but renders the instructions generated by the source,
+// without storing them. The source generates its instructions twice: once to
+// compute the image's bounds and once to draw it.
+func SaveStreamAsPNG(source InstructionSource, pixelsTall int,
+	out io.Writer) error {
+	return SaveStreamAsPNGContext(context.Background(), source, pixelsTall,
+		out, nil)
+}
+
+// Like SaveStreamAsPNG, but uses RenderStreamContext so rendering can be
+// canceled using ctx. Progress is reported in the same way as
+// SaveTurtleAsPNGContext, except that the total is -1 during the first pass,
+// before the number of instructions is known. As for RenderStreamContext, the
+// turtle's seed and TurtleOptions are taken from opts.
+func SaveStreamAsPNGContext(ctx context.Context, source InstructionSource,
+	pixelsTall int, out io.Writer, opts *RenderOptions) error {
+	return renderPNG(pixelsTall, out, opts,
+		func(c Canvas, passOptions *RenderOptions) error {
+			return RenderStreamContext(ctx, source, c, passOptions)
+		})
+}
+
+// Carries out the work of SaveTurtleAsPNGContext and SaveStreamAsPNGContext.
+// The render function is called twice, first with a DummyCanvas and then with
+// an RGBACanvas, and must draw the same thing both times.
+func renderPNG(pixelsTall int, out io.Writer, opts *RenderOptions,
+	render func(c Canvas, passOptions *RenderOptions) error) error {
 	if pixelsTall <= 0 {
 		return fmt.Errorf("Image height in pixels must be positive")
 	}
+	firstPassOptions := &RenderOptions{}
+	secondPassOptions := &RenderOptions{}
+	if opts != nil {
+		*firstPassOptions = *opts
+		*secondPassOptions = *opts
+	}
+	if firstPassOptions.Progress != nil {
+		// The first pass always ends by reporting the total number of
+		// instructions, which is needed for the second pass.
+		count := 0
+		progress := firstPassOptions.Progress
+		firstPassOptions.Progress = func(done, total int) {
+			count = done
+			if total < 0 {
+				progress(done, -1)
+				return
+			}
+			progress(done, 2*total)
+		}
+		secondPassOptions.Progress = func(done, total int) {
+			progress(count+done, 2*count)
+		}
+	}
 
 	// Get a dummy canvas to compute the image bounds with.
 	dummyCanvas := NewDummyCanvas()
-	e := t.RenderToCanvas(dummyCanvas)
+	e := render(dummyCanvas, firstPassOptions)
 	if e != nil {
-		return fmt.Errorf("Failed rendering to dummy canvas: %s", e)
+		return fmt.Errorf("Failed rendering to dummy canvas: %w", e)
 	}
 	minX, minY, maxX, maxY := dummyCanvas.GetExtents()
 	aspectRatio := (maxX - minX) / (maxY - minY)
@@ -233,9 +536,9 @@ func SaveTurtleAsPNG(t *Turtle, pixelsTall int, out io.Writer) error {
 	if e != nil {
 		return fmt.Errorf("Failed initializing RGBA canvas: %s", e)
 	}
-	e = t.RenderToCanvas(rgbaCanvas)
+	e = render(rgbaCanvas, secondPassOptions)
 	if e != nil {
-		return fmt.Errorf("Failed rendering to RGBA canvas: %s", e)
+		return fmt.Errorf("Failed rendering to RGBA canvas: %w", e)
 	}
 	e = png.Encode(out, rgbaCanvas)
 	if e != nil {
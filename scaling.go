@@ -0,0 +1,130 @@
+package turtle_graphics
+
+// This file contains instructions that scale the distance the turtle moves
+// and the width of the lines it draws, as used by L-systems whose branches
+// get shorter and thinner as they get deeper. Both are part of the turtle's
+// position, so they're saved and restored by PushPosition and PopPosition.
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// A StrokeStyle that also specifies the width of lines, in the same units as
+// the canvas's coordinates. Canvases that support wide lines should check
+// whether the style passed to SetStyle implements this interface. A width of
+// 0 or less means the thinnest line the canvas can draw, which is also how
+// canvases should treat styles that don't implement this interface.
+type WidthStrokeStyle interface {
+	StrokeStyle
+	// Returns the width of the stroke.
+	GetWidth() float64
+}
+
+// Adds a width to another StrokeStyle.
+type widthStrokeStyle struct {
+	StrokeStyle
+	width float64
+}
+
+func (s *widthStrokeStyle) GetWidth() float64 {
+	return s.width
+}
+
+// Returns the style that should be passed to the canvas: the most recent style
+// set by the turtle, with the turtle's line width. Until a style is set,
+// lines are drawn in black.
+func (s *TurtleState) canvasStyle() StrokeStyle {
+	style := s.position.style
+	if style == nil {
+		style = GetColorStyle(color.Black)
+	}
+	if s.position.lineWidth <= 0 {
+		return style
+	}
+	return &widthStrokeStyle{
+		StrokeStyle: style,
+		width:       s.position.lineWidth,
+	}
+}
+
+// Changes the turtle's line width, passing the new width to the canvas if it
+// changed.
+func (s *TurtleState) setLineWidth(c Canvas, width float64) error {
+	if width < 0 {
+		width = 0
+	}
+	if width == s.position.lineWidth {
+		return nil
+	}
+	s.position.lineWidth = width
+	return c.SetStyle(s.canvasStyle())
+}
+
+// An instruction multiplying the turtle's step scale by a factor.
+type scaleStepInstruction struct {
+	factor float64
+}
+
+func (n *scaleStepInstruction) String() string {
+	return fmt.Sprintf("Scale step by %f", n.factor)
+}
+
+func (n *scaleStepInstruction) Apply(s *TurtleState, c Canvas) error {
+	s.position.stepScale *= n.factor
+	return nil
+}
+
+// An instruction setting the width of the lines the turtle draws.
+type setLineWidthInstruction struct {
+	width float64
+}
+
+func (n *setLineWidthInstruction) String() string {
+	return fmt.Sprintf("Set line width to %f", n.width)
+}
+
+func (n *setLineWidthInstruction) Apply(s *TurtleState, c Canvas) error {
+	return s.setLineWidth(c, n.width)
+}
+
+// An instruction multiplying the width of the lines the turtle draws by a
+// factor.
+type scaleLineWidthInstruction struct {
+	factor float64
+}
+
+func (n *scaleLineWidthInstruction) String() string {
+	return fmt.Sprintf("Scale line width by %f", n.factor)
+}
+
+func (n *scaleLineWidthInstruction) Apply(s *TurtleState, c Canvas) error {
+	return s.setLineWidth(c, s.position.lineWidth*n.factor)
+}
+
+// Adds an instruction to multiply the turtle's step scale by the given factor.
+// The step scale starts at 1, and multiplies the distances moved by MoveForward
+// and MoveForwardRandom, the radius of MoveArc, the offsets of QuadraticBezier
+// and CubicBezier, and the height of text. It doesn't affect absolute positions
+// or dots. The step scale is saved by PushPosition and restored by PopPosition,
+// so a branch can shorten its steps without affecting the rest of the drawing.
+func (t *Turtle) ScaleStep(factor float64) {
+	t.addOp(opScaleStep, nil, factor)
+}
+
+// Adds an instruction to set the width of the lines drawn by the turtle, in
+// the same units as its position. The width starts at 0, which draws the
+// thinnest lines the canvas supports. Other widths are passed to the canvas
+// along with the current style, as a WidthStrokeStyle; if no style has been
+// set, the lines are black. Like the step scale, the width is saved by
+// PushPosition and restored by PopPosition.
+func (t *Turtle) SetLineWidth(width float64) {
+	t.addOp(opSetLineWidth, nil, width)
+}
+
+// Adds an instruction to multiply the width of the lines drawn by the turtle
+// by the given factor. Has no effect while the width is 0, so SetLineWidth
+// must be used first.
+func (t *Turtle) ScaleLineWidth(factor float64) {
+	t.addOp(opScaleLineWidth, nil, factor)
+}
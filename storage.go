@@ -0,0 +1,517 @@
+package turtle_graphics
+
+// This file contains the compact representation used to store a Turtle's
+// instructions. Rather than storing each instruction as a separately
+// allocated struct behind an interface, each instruction is stored as a
+// one-byte opcode, followed by a fixed number of float64 operands and
+// optionally a single object, such as a style, in separate arrays. The
+// instruction types that implement Instruction are only created when needed,
+// for example when describing instructions or converting them to text.
+
+// Identifies the type of an instruction in an instructionList.
+type opcode uint8
+
+const (
+	// A user-defined instruction. The object is the Instruction.
+	opCustom opcode = iota
+	// Operands: distance.
+	opMoveForward
+	// Operands: degrees.
+	opTurn
+	// The object is the StrokeStyle.
+	opSetStyle
+	// Operands: radius, degrees.
+	opMoveArc
+	// Operands: x, y.
+	opGoTo
+	// Operands: x, y.
+	opJumpTo
+	// Operands: degrees.
+	opSetHeading
+	// Operands: x, y.
+	opFaceTowards
+	opHome
+	opPenUp
+	opPenDown
+	opPushPosition
+	opPopPosition
+	// Operands: min, max.
+	opTurnRandom
+	// Operands: min, max.
+	opMoveForwardRandom
+	// Operands: probability. The object is the subprogram's *Turtle.
+	opChance
+	// Operands: fill rule. The object is the fill's StrokeStyle.
+	opBeginFill
+	opEndFill
+	// Operands: the four offsets of a quadratic bezierInstruction.
+	opQuadraticBezier
+	// Operands: the six offsets of a cubic bezierInstruction.
+	opCubicBezier
+	// Operands: height. The object is the text, as a string.
+	opWriteText
+	// Operands: diameter.
+	opDot
+	// The object is the shape's *Turtle.
+	opStamp
+	// Operands: factor.
+	opScaleStep
+	// Operands: width.
+	opSetLineWidth
+	// Operands: factor.
+	opScaleLineWidth
+	// Operands: scale. The object is the sub turtle's *Turtle.
+	opCall
+	// Operands: depth, scale. The object is the *procedure.
+	opCallRecursive
+	// Operands: scale.
+	opRecurse
+	// Operands: the XX, YX, XY, YY, X0 and Y0 fields of the AffineTransform.
+	opTransform
+)
+
+// The amount of storage used by each opcode's instructions.
+type opcodeSize struct {
+	// The number of float64 operands.
+	operands int
+	// Whether the instruction has an object.
+	object bool
+}
+
+// Maps each opcode to the size of its instructions.
+var opcodeSizes = [...]opcodeSize{
+	opCustom:            {0, true},
+	opMoveForward:       {1, false},
+	opTurn:              {1, false},
+	opSetStyle:          {0, true},
+	opMoveArc:           {2, false},
+	opGoTo:              {2, false},
+	opJumpTo:            {2, false},
+	opSetHeading:        {1, false},
+	opFaceTowards:       {2, false},
+	opHome:              {0, false},
+	opPenUp:             {0, false},
+	opPenDown:           {0, false},
+	opPushPosition:      {0, false},
+	opPopPosition:       {0, false},
+	opTurnRandom:        {2, false},
+	opMoveForwardRandom: {2, false},
+	opChance:            {1, true},
+	opBeginFill:         {1, true},
+	opEndFill:           {0, false},
+	opQuadraticBezier:   {4, false},
+	opCubicBezier:       {6, false},
+	opWriteText:         {1, true},
+	opDot:               {1, false},
+	opStamp:             {0, true},
+	opScaleStep:         {1, false},
+	opSetLineWidth:      {1, false},
+	opScaleLineWidth:    {1, false},
+	opCall:              {1, true},
+	opCallRecursive:     {2, true},
+	opRecurse:           {1, false},
+	opTransform:         {6, false},
+}
+
+// Returns the opcode, object and operands used to store the given
+// instruction. Instructions that aren't built in are stored using opCustom.
+func encodeInstruction(n Instruction) (opcode, interface{}, []float64) {
+	switch v := n.(type) {
+	case *moveForwardInstruction:
+		return opMoveForward, nil, []float64{v.distance}
+	case *turnInstruction:
+		return opTurn, nil, []float64{v.degrees}
+	case *setStyleInstruction:
+		return opSetStyle, v.style, nil
+	case *moveArcInstruction:
+		return opMoveArc, nil, []float64{v.radius, v.degrees}
+	case *goToInstruction:
+		if v.draw {
+			return opGoTo, nil, []float64{v.x, v.y}
+		}
+		return opJumpTo, nil, []float64{v.x, v.y}
+	case *setHeadingInstruction:
+		return opSetHeading, nil, []float64{v.degrees}
+	case *faceTowardsInstruction:
+		return opFaceTowards, nil, []float64{v.x, v.y}
+	case *homeInstruction:
+		return opHome, nil, nil
+	case *setPenInstruction:
+		if v.up {
+			return opPenUp, nil, nil
+		}
+		return opPenDown, nil, nil
+	case *pushPositionInstruction:
+		return opPushPosition, nil, nil
+	case *popPositionInstruction:
+		return opPopPosition, nil, nil
+	case *turnRandomInstruction:
+		return opTurnRandom, nil, []float64{v.min, v.max}
+	case *moveForwardRandomInstruction:
+		return opMoveForwardRandom, nil, []float64{v.min, v.max}
+	case *chanceInstruction:
+		return opChance, v.subprogram, []float64{v.probability}
+	case *beginFillInstruction:
+		return opBeginFill, v.style, []float64{float64(v.rule)}
+	case *endFillInstruction:
+		return opEndFill, nil, nil
+	case *bezierInstruction:
+		if v.cubic {
+			return opCubicBezier, nil, v.offsets[:]
+		}
+		return opQuadraticBezier, nil, v.offsets[0:4]
+	case *writeTextInstruction:
+		return opWriteText, v.text, []float64{v.height}
+	case *dotInstruction:
+		return opDot, nil, []float64{v.diameter}
+	case *stampInstruction:
+		return opStamp, v.shape, nil
+	case *scaleStepInstruction:
+		return opScaleStep, nil, []float64{v.factor}
+	case *setLineWidthInstruction:
+		return opSetLineWidth, nil, []float64{v.width}
+	case *scaleLineWidthInstruction:
+		return opScaleLineWidth, nil, []float64{v.factor}
+	case *callInstruction:
+		return opCall, v.sub, []float64{v.scale}
+	case *callRecursiveInstruction:
+		return opCallRecursive, v.procedure, []float64{float64(v.depth),
+			v.scale}
+	case *recurseInstruction:
+		return opRecurse, nil, []float64{v.scale}
+	case *transformInstruction:
+		m := v.m
+		return opTransform, nil, []float64{m.XX, m.YX, m.XY, m.YY, m.X0, m.Y0}
+	}
+	return opCustom, n, nil
+}
+
+// Returns a newly allocated Instruction equivalent to the stored instruction
+// with the given opcode, operands and object. The inverse of
+// encodeInstruction.
+func decodeInstruction(op opcode, operands []float64,
+	object interface{}) Instruction {
+	switch op {
+	case opMoveForward:
+		return &moveForwardInstruction{distance: operands[0]}
+	case opTurn:
+		return &turnInstruction{degrees: operands[0]}
+	case opSetStyle:
+		// The style may be a nil interface, which can't be asserted.
+		style, _ := object.(StrokeStyle)
+		return &setStyleInstruction{style: style}
+	case opMoveArc:
+		return &moveArcInstruction{radius: operands[0], degrees: operands[1]}
+	case opGoTo:
+		return &goToInstruction{x: operands[0], y: operands[1], draw: true}
+	case opJumpTo:
+		return &goToInstruction{x: operands[0], y: operands[1], draw: false}
+	case opSetHeading:
+		return &setHeadingInstruction{degrees: operands[0]}
+	case opFaceTowards:
+		return &faceTowardsInstruction{x: operands[0], y: operands[1]}
+	case opHome:
+		return &homeInstruction{}
+	case opPenUp:
+		return &setPenInstruction{up: true}
+	case opPenDown:
+		return &setPenInstruction{up: false}
+	case opPushPosition:
+		return &pushPositionInstruction{}
+	case opPopPosition:
+		return &popPositionInstruction{}
+	case opTurnRandom:
+		return &turnRandomInstruction{min: operands[0], max: operands[1]}
+	case opMoveForwardRandom:
+		return &moveForwardRandomInstruction{
+			min: operands[0],
+			max: operands[1],
+		}
+	case opChance:
+		return &chanceInstruction{
+			probability: operands[0],
+			subprogram:  object.(*Turtle),
+		}
+	case opBeginFill:
+		style, _ := object.(StrokeStyle)
+		return &beginFillInstruction{
+			style: style,
+			rule:  FillRule(operands[0]),
+		}
+	case opEndFill:
+		return &endFillInstruction{}
+	case opQuadraticBezier, opCubicBezier:
+		n := &bezierInstruction{cubic: op == opCubicBezier}
+		copy(n.offsets[:], operands)
+		return n
+	case opWriteText:
+		return &writeTextInstruction{
+			text:   object.(string),
+			height: operands[0],
+		}
+	case opDot:
+		return &dotInstruction{diameter: operands[0]}
+	case opStamp:
+		return &stampInstruction{shape: object.(*Turtle)}
+	case opScaleStep:
+		return &scaleStepInstruction{factor: operands[0]}
+	case opSetLineWidth:
+		return &setLineWidthInstruction{width: operands[0]}
+	case opScaleLineWidth:
+		return &scaleLineWidthInstruction{factor: operands[0]}
+	case opCall:
+		return &callInstruction{sub: object.(*Turtle), scale: operands[0]}
+	case opCallRecursive:
+		return &callRecursiveInstruction{
+			procedure: object.(*procedure),
+			depth:     int(operands[0]),
+			scale:     operands[1],
+		}
+	case opRecurse:
+		return &recurseInstruction{scale: operands[0]}
+	case opTransform:
+		return &transformInstruction{m: transformFromOperands(operands)}
+	}
+	return object.(Instruction)
+}
+
+// Carries out a stored instruction, drawing in the turtle's frame. The most
+// common instructions are carried out without allocating an Instruction; the
+// rest are decoded first.
+func executeInstruction(s *TurtleState, c Canvas, op opcode,
+	operands []float64, object interface{}) error {
+	c = s.frameCanvas(c)
+	switch op {
+	case opMoveForward:
+		n := moveForwardInstruction{distance: operands[0]}
+		return n.Apply(s, c)
+	case opTurn:
+		n := turnInstruction{degrees: operands[0]}
+		return n.Apply(s, c)
+	case opMoveArc:
+		n := moveArcInstruction{radius: operands[0], degrees: operands[1]}
+		return n.Apply(s, c)
+	case opGoTo, opJumpTo:
+		n := goToInstruction{
+			x:    operands[0],
+			y:    operands[1],
+			draw: op == opGoTo,
+		}
+		return n.Apply(s, c)
+	case opSetHeading:
+		n := setHeadingInstruction{degrees: operands[0]}
+		return n.Apply(s, c)
+	case opPenUp, opPenDown:
+		n := setPenInstruction{up: op == opPenUp}
+		return n.Apply(s, c)
+	case opPushPosition:
+		n := pushPositionInstruction{}
+		return n.Apply(s, c)
+	case opPopPosition:
+		n := popPositionInstruction{}
+		return n.Apply(s, c)
+	case opTurnRandom:
+		n := turnRandomInstruction{min: operands[0], max: operands[1]}
+		return n.Apply(s, c)
+	case opMoveForwardRandom:
+		n := moveForwardRandomInstruction{min: operands[0], max: operands[1]}
+		return n.Apply(s, c)
+	case opRecurse:
+		n := recurseInstruction{scale: operands[0]}
+		return n.Apply(s, c)
+	case opCustom:
+		return object.(Instruction).Apply(s, c)
+	}
+	return decodeInstruction(op, operands, object).Apply(s, c)
+}
+
+// Holds a list of instructions in a compact form. The operands and objects of
+// each instruction follow those of the previous instruction, in the order of
+// the opcodes.
+type instructionList struct {
+	ops      []opcode
+	operands []float64
+	objects  []interface{}
+}
+
+// The number of instructions that a new turtle has room for. Kept small, since
+// many turtles are only short subprograms, and instruction lists grow as
+// needed.
+const initialInstructionCapacity = 16
+
+// Returns an empty instructionList with room for the given number of
+// instructions.
+func newInstructionList(capacity int) instructionList {
+	return instructionList{
+		ops:      make([]opcode, 0, capacity),
+		operands: make([]float64, 0, capacity),
+		objects:  nil,
+	}
+}
+
+// Adds all of the instructions in another list to the end of this list. The
+// other list may be this list.
+func (l *instructionList) addList(other *instructionList) {
+	l.ops = append(l.ops, other.ops...)
+	l.operands = append(l.operands, other.operands...)
+	l.objects = append(l.objects, other.objects...)
+}
+
+// Returns the number of instructions in the list.
+func (l *instructionList) len() int {
+	return len(l.ops)
+}
+
+// Adds an instruction to the end of the list. The object is ignored if the
+// opcode doesn't use one.
+func (l *instructionList) add(op opcode, object interface{},
+	operands ...float64) {
+	l.ops = append(l.ops, op)
+	l.operands = append(l.operands, operands...)
+	if opcodeSizes[op].object {
+		l.objects = append(l.objects, object)
+	}
+}
+
+// Adds the given Instruction to the end of the list, storing it compactly if
+// it's built in.
+func (l *instructionList) addInstruction(n Instruction) {
+	op, object, operands := encodeInstruction(n)
+	l.add(op, object, operands...)
+}
+
+// Adds the given instructions to the end of the list.
+func (l *instructionList) addInstructions(instructions []Instruction) {
+	for _, n := range instructions {
+		l.addInstruction(n)
+	}
+}
+
+// Used to step through an instructionList, keeping track of the position of
+// each instruction's operands and object.
+type instructionCursor struct {
+	list *instructionList
+	// The index of the current instruction.
+	index int
+	// The positions of the current instruction's operands and object.
+	operandIndex, objectIndex int
+}
+
+// Returns a cursor positioned at the first instruction in the list.
+func (l *instructionList) cursor() instructionCursor {
+	return instructionCursor{
+		list: l,
+	}
+}
+
+// Returns true if the cursor is positioned at an instruction, or false if it
+// has passed the end of the list.
+func (c *instructionCursor) valid() bool {
+	return c.index < len(c.list.ops)
+}
+
+// Returns the current instruction's opcode, operands and object.
+func (c *instructionCursor) get() (opcode, []float64, interface{}) {
+	op := c.list.ops[c.index]
+	size := opcodeSizes[op]
+	operands := c.list.operands[c.operandIndex : c.operandIndex+size.operands]
+	var object interface{}
+	if size.object {
+		object = c.list.objects[c.objectIndex]
+	}
+	return op, operands, object
+}
+
+// Moves the cursor to the next instruction.
+func (c *instructionCursor) next() {
+	size := opcodeSizes[c.list.ops[c.index]]
+	c.operandIndex += size.operands
+	if size.object {
+		c.objectIndex++
+	}
+	c.index++
+}
+
+// Returns a cursor positioned at the instruction with the given index, which
+// may equal the length of the list.
+func (l *instructionList) cursorAt(index int) instructionCursor {
+	c := l.cursor()
+	for c.index < index {
+		c.next()
+	}
+	return c
+}
+
+// Returns the instruction at the cursor's position as an Instruction.
+func (c *instructionCursor) instruction() Instruction {
+	return decodeInstruction(c.get())
+}
+
+// Returns all of the instructions in the list as Instructions.
+func (l *instructionList) decodeAll() []Instruction {
+	toReturn := make([]Instruction, 0, len(l.ops))
+	for c := l.cursor(); c.valid(); c.next() {
+		toReturn = append(toReturn, c.instruction())
+	}
+	return toReturn
+}
+
+// Discards all but the first n instructions. n must be between 0 and the
+// length of the list.
+func (l *instructionList) truncate(n int) {
+	c := l.cursorAt(n)
+	// Clear the discarded objects so they can be garbage collected.
+	for i := c.objectIndex; i < len(l.objects); i++ {
+		l.objects[i] = nil
+	}
+	l.ops = l.ops[0:n]
+	l.operands = l.operands[0:c.operandIndex]
+	l.objects = l.objects[0:c.objectIndex]
+}
+
+// Inserts the given instructions before the instruction at the given index,
+// which may equal the length of the list.
+func (l *instructionList) insert(index int, instructions []Instruction) {
+	tail := newInstructionList(0)
+	c := l.cursorAt(index)
+	for ; c.valid(); c.next() {
+		op, operands, object := c.get()
+		tail.add(op, object, operands...)
+	}
+	l.truncate(index)
+	l.addInstructions(instructions)
+	l.ops = append(l.ops, tail.ops...)
+	l.operands = append(l.operands, tail.operands...)
+	l.objects = append(l.objects, tail.objects...)
+}
+
+// Removes the instruction at the given index, which must be in the list.
+func (l *instructionList) remove(index int) {
+	c := l.cursorAt(index)
+	size := opcodeSizes[l.ops[index]]
+	l.ops = append(l.ops[0:index], l.ops[index+1:]...)
+	l.operands = append(l.operands[0:c.operandIndex],
+		l.operands[c.operandIndex+size.operands:]...)
+	if size.object {
+		// Clear the last entry after shifting the others, so the removed
+		// object can be garbage collected.
+		last := len(l.objects) - 1
+		copy(l.objects[c.objectIndex:], l.objects[c.objectIndex+1:])
+		l.objects[last] = nil
+		l.objects = l.objects[0:last]
+	}
+}
+
+// Carries out all of the instructions in the list, as part of a larger
+// program. Returns an *InstructionError identifying the failing instruction
+// by its index in the list.
+func (l *instructionList) run(s *TurtleState, c Canvas) error {
+	for cursor := l.cursor(); cursor.valid(); cursor.next() {
+		op, operands, object := cursor.get()
+		e := executeInstruction(s, c, op, operands, object)
+		if e != nil {
+			return newInstructionError(cursor.index, cursor.instruction(), e)
+		}
+	}
+	return nil
+}
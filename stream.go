@@ -0,0 +1,131 @@
+package turtle_graphics
+
+// This file contains the code for rendering instructions as they're
+// generated, without storing them in a Turtle.
+
+import (
+	"context"
+	"fmt"
+)
+
+// A source of turtle instructions that are generated on demand, rather than
+// stored. Used by RenderStream to render programs that are too large to keep
+// in memory.
+type InstructionSource interface {
+	// Generates the program's instructions by calling the given turtle's
+	// methods, such as MoveForward and Turn. Each instruction is carried out
+	// as soon as it's added, and then discarded. Generate is called once for
+	// each rendering pass, so it must produce the same instructions every
+	// time it's called.
+	Generate(t *Turtle) error
+}
+
+// Allows an ordinary function to be used as an InstructionSource.
+type InstructionSourceFunc func(t *Turtle) error
+
+func (f InstructionSourceFunc) Generate(t *Turtle) error {
+	return f(t)
+}
+
+// Carries out instructions as they're added to a streaming Turtle.
+type instructionStream struct {
+	ctx           context.Context
+	state         *TurtleState
+	canvas        Canvas
+	progress      func(done, total int)
+	checkInterval int
+	// The number of instructions carried out so far.
+	count int
+	// The first error that occurred, after which further instructions are
+	// ignored.
+	err error
+}
+
+// Carries out the given instruction, unless an earlier instruction failed or
+// the context has been canceled.
+func (s *instructionStream) run(op opcode, operands []float64,
+	object interface{}) {
+	if s.err != nil {
+		return
+	}
+	if (s.count % s.checkInterval) == 0 {
+		e := s.ctx.Err()
+		if e != nil {
+			s.err = fmt.Errorf("Rendering stopped after %d instructions: %w",
+				s.count, e)
+			return
+		}
+		if (s.progress != nil) && (s.count != 0) {
+			s.progress(s.count, -1)
+		}
+	}
+	e := executeInstruction(s.state, s.canvas, op, operands, object)
+	if e != nil {
+		s.err = newInstructionError(s.count,
+			decodeInstruction(op, operands, object), e)
+		return
+	}
+	s.count++
+}
+
+// Returns the error that stopped a streaming render, if any. Generators passed
+// to RenderStream may check this to stop generating instructions early, for
+// example after the context has been canceled, since any further instructions
+// would be ignored. Always returns nil for turtles that aren't streaming.
+func (t *Turtle) Err() error {
+	if t.stream == nil {
+		return nil
+	}
+	return t.stream.err
+}
+
+// Renders the instructions generated by the source to the given canvas,
+// carrying out each instruction as soon as it is added rather than storing
+// it. So, the turtle passed to the source's Generate function never contains
+// any instructions, and methods that inspect or edit its instruction list
+// have no effect on the drawing. The turtle uses a seed of 0 and the default
+// TurtleOptions; use RenderStreamContext to choose others. If an instruction
+// fails, returns an *InstructionError whose Index counts the instructions
+// generated before it.
+func RenderStream(source InstructionSource, c Canvas) error {
+	return RenderStreamContext(context.Background(), source, c, nil)
+}
+
+// Like RenderStream, but checks whether ctx has been canceled and reports
+// progress in the same way as Turtle.RenderToCanvasContext. The total number
+// of instructions isn't known until the source finishes generating them, so
+// the total passed to the Progress function is -1 until the final call. The
+// turtle's seed and TurtleOptions are taken from opts, which may be nil to use
+// default options.
+func RenderStreamContext(ctx context.Context, source InstructionSource,
+	c Canvas, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	checkInterval := opts.CheckInterval
+	if checkInterval <= 0 {
+		checkInterval = DefaultCheckInterval
+	}
+	t := NewTurtleWithOptions(opts.TurtleOptions)
+	t.seed = opts.Seed
+	t.stream = &instructionStream{
+		ctx:           ctx,
+		state:         newTurtleState(t.seed, t.start),
+		canvas:        c,
+		progress:      opts.Progress,
+		checkInterval: checkInterval,
+	}
+	e := source.Generate(t)
+	// An error while rendering is more informative than any error it caused
+	// the generator to return.
+	if t.stream.err != nil {
+		return t.stream.err
+	}
+	if e != nil {
+		return fmt.Errorf("Failed generating instructions: %w", e)
+	}
+	if opts.Progress != nil {
+		opts.Progress(t.stream.count, t.stream.count)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package turtle_graphics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// Generates a program whose drawing depends on the turtle's seed, starting
+// position and options.
+func generateStreamTestProgram(t *Turtle) error {
+	for i := 0; i < 20; i++ {
+		t.MoveForwardRandom(0.5, 1.5)
+		t.TurnRandom(0.1, 0.3)
+		t.MoveArc(0.5, 0.5)
+		if (i % 7) == 0 {
+			t.Home()
+			t.SetHeading(0.2)
+		}
+	}
+	t.Add(&recordStateTestInstruction{})
+	return nil
+}
+
+func TestStreamMatchesStoredTurtle(t *testing.T) {
+	source := InstructionSourceFunc(generateStreamTestProgram)
+	options := TurtleOptions{
+		AngleUnit:   Radians,
+		Orientation: LogoOrientation,
+		X:           1,
+		Y:           2,
+		Heading:     0.5,
+	}
+	stored := NewTurtleWithOptions(options)
+	stored.SetSeed(1337)
+	generateStreamTestProgram(stored)
+	expected := getCanvasCalls(t, stored)
+
+	opts := &RenderOptions{
+		Seed:          1337,
+		TurtleOptions: options,
+	}
+	c := &recordingCanvas{}
+	e := RenderStreamContext(context.Background(), source, c, opts)
+	if e != nil {
+		t.Fatalf("Failed rendering stream: %s", e)
+	}
+	if len(c.calls) != len(expected) {
+		t.Fatalf("Expected %d canvas calls, got %d", len(expected),
+			len(c.calls))
+	}
+	for i := range expected {
+		if c.calls[i] != expected[i] {
+			t.Fatalf("Expected canvas call %d to be %q, got %q", i,
+				expected[i], c.calls[i])
+		}
+	}
+
+	var b bytes.Buffer
+	e = SaveStreamAsPNGContext(context.Background(), source, 200, &b, opts)
+	if e != nil {
+		t.Fatalf("Failed saving stream as PNG: %s", e)
+	}
+	if !bytes.Equal(b.Bytes(), getTurtlePNG(t, stored)) {
+		t.Errorf("The stream's PNG differs from the stored turtle's")
+	}
+
+	// Without options, the stream matches a turtle created using NewTurtle.
+	stored = NewTurtle()
+	generateStreamTestProgram(stored)
+	b.Reset()
+	e = SaveStreamAsPNG(source, 200, &b)
+	if e != nil {
+		t.Fatalf("Failed saving stream as PNG: %s", e)
+	}
+	if !bytes.Equal(b.Bytes(), getTurtlePNG(t, stored)) {
+		t.Errorf("The stream's PNG differs from the default turtle's")
+	}
+	e = RenderStream(source, NewDummyCanvas())
+	if e != nil {
+		t.Errorf("Failed rendering stream to a DummyCanvas: %s", e)
+	}
+}
@@ -0,0 +1,752 @@
+package turtle_graphics
+
+// This file contains the code for converting a Turtle's instructions to and
+// from a simple line-oriented text format. Each line contains a single
+// instruction: a keyword followed by space-separated operands, for example:
+//
+//    forward 1
+//    turn 90
+//    arc 0.25 180
+//    push
+//    pop
+//    style #ff0000
+//
+// Blank lines and lines starting with '#' are ignored. Subprograms, such as
+// those used by Turtle.Chance, are written as named definitions enclosed in
+// braces, and must be defined before they are used.
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Formats a float64 so that it can be parsed back to exactly the same value.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Formats a color as #rrggbb, or #rrggbbaa if it isn't fully opaque.
+func formatColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.R, n.G, n.B, n.A)
+}
+
+// Parses a color in the #rrggbb or #rrggbbaa format.
+func parseColor(s string) (color.Color, error) {
+	if !strings.HasPrefix(s, "#") || ((len(s) != 7) && (len(s) != 9)) {
+		return nil, fmt.Errorf("Invalid color %q: expected #rrggbb or "+
+			"#rrggbbaa", s)
+	}
+	v, e := strconv.ParseUint(s[1:], 16, 32)
+	if e != nil {
+		return nil, fmt.Errorf("Invalid color %q: %w", s, e)
+	}
+	if len(s) == 7 {
+		v = (v << 8) | 0xff
+	}
+	return color.NRGBA{
+		R: uint8(v >> 24),
+		G: uint8(v >> 16),
+		B: uint8(v >> 8),
+		A: uint8(v),
+	}, nil
+}
+
+func (n *moveForwardInstruction) marshalText(w *textEncoder) (string, error) {
+	return "forward " + formatFloat(n.distance), nil
+}
+
+func (n *turnInstruction) marshalText(w *textEncoder) (string, error) {
+	return "turn " + formatFloat(n.degrees), nil
+}
+
+// Formats a stroke style, which must have been created by GetColorStyle.
+func formatStyle(style StrokeStyle) (string, error) {
+	s, ok := style.(*basicStrokeStyle)
+	if !ok {
+		return "", fmt.Errorf("Unsupported stroke style type %T: only "+
+			"styles from GetColorStyle can be converted to text", style)
+	}
+	return formatColor(s.c), nil
+}
+
+func (n *setStyleInstruction) marshalText(w *textEncoder) (string, error) {
+	style, e := formatStyle(n.style)
+	if e != nil {
+		return "", e
+	}
+	return "style " + style, nil
+}
+
+func (n *moveArcInstruction) marshalText(w *textEncoder) (string, error) {
+	return "arc " + formatFloat(n.radius) + " " + formatFloat(n.degrees), nil
+}
+
+func (n *goToInstruction) marshalText(w *textEncoder) (string, error) {
+	keyword := "goto "
+	if !n.draw {
+		keyword = "jumpto "
+	}
+	return keyword + formatFloat(n.x) + " " + formatFloat(n.y), nil
+}
+
+func (n *setHeadingInstruction) marshalText(w *textEncoder) (string, error) {
+	return "heading " + formatFloat(n.degrees), nil
+}
+
+func (n *faceTowardsInstruction) marshalText(w *textEncoder) (string, error) {
+	return "face " + formatFloat(n.x) + " " + formatFloat(n.y), nil
+}
+
+func (n *homeInstruction) marshalText(w *textEncoder) (string, error) {
+	return "home", nil
+}
+
+func (n *setPenInstruction) marshalText(w *textEncoder) (string, error) {
+	if n.up {
+		return "penup", nil
+	}
+	return "pendown", nil
+}
+
+func (n *pushPositionInstruction) marshalText(w *textEncoder) (string, error) {
+	return "push", nil
+}
+
+func (n *popPositionInstruction) marshalText(w *textEncoder) (string, error) {
+	return "pop", nil
+}
+
+func (n *turnRandomInstruction) marshalText(w *textEncoder) (string, error) {
+	return "randomturn " + formatFloat(n.min) + " " + formatFloat(n.max), nil
+}
+
+func (n *moveForwardRandomInstruction) marshalText(
+	w *textEncoder) (string, error) {
+	return "randomforward " + formatFloat(n.min) + " " + formatFloat(n.max),
+		nil
+}
+
+func (n *beginFillInstruction) marshalText(w *textEncoder) (string, error) {
+	style, e := formatStyle(n.style)
+	if e != nil {
+		return "", e
+	}
+	return "beginfill " + style + " " + n.rule.String(), nil
+}
+
+func (n *endFillInstruction) marshalText(w *textEncoder) (string, error) {
+	return "endfill", nil
+}
+
+func (n *bezierInstruction) marshalText(w *textEncoder) (string, error) {
+	keyword := "quadratic"
+	operands := n.offsets[0:4]
+	if n.cubic {
+		keyword = "cubic"
+		operands = n.offsets[:]
+	}
+	for _, v := range operands {
+		keyword += " " + formatFloat(v)
+	}
+	return keyword, nil
+}
+
+func (n *writeTextInstruction) marshalText(w *textEncoder) (string,
+	error) {
+	return "text " + formatFloat(n.height) + " " + strconv.Quote(n.text), nil
+}
+
+func (n *dotInstruction) marshalText(w *textEncoder) (string, error) {
+	return "dot " + formatFloat(n.diameter), nil
+}
+
+func (n *stampInstruction) marshalText(w *textEncoder) (string, error) {
+	name, e := w.subprogramName(n.shape)
+	if e != nil {
+		return "", e
+	}
+	return "stamp " + name, nil
+}
+
+func (n *scaleStepInstruction) marshalText(w *textEncoder) (string, error) {
+	return "scalestep " + formatFloat(n.factor), nil
+}
+
+func (n *setLineWidthInstruction) marshalText(w *textEncoder) (string,
+	error) {
+	return "width " + formatFloat(n.width), nil
+}
+
+func (n *scaleLineWidthInstruction) marshalText(w *textEncoder) (string,
+	error) {
+	return "scalewidth " + formatFloat(n.factor), nil
+}
+
+func (n *callInstruction) marshalText(w *textEncoder) (string, error) {
+	name, e := w.subprogramName(n.sub)
+	if e != nil {
+		return "", e
+	}
+	return "call " + formatFloat(n.scale) + " " + name, nil
+}
+
+func (n *callRecursiveInstruction) marshalText(w *textEncoder) (string,
+	error) {
+	body, e := w.subprogramName(n.procedure.body)
+	if e != nil {
+		return "", e
+	}
+	line := "callrecursive " + strconv.Itoa(n.depth) + " " +
+		formatFloat(n.scale) + " " + body
+	if n.procedure.base == nil {
+		return line, nil
+	}
+	base, e := w.subprogramName(n.procedure.base)
+	if e != nil {
+		return "", e
+	}
+	return line + " " + base, nil
+}
+
+func (n *recurseInstruction) marshalText(w *textEncoder) (string, error) {
+	return "recurse " + formatFloat(n.scale), nil
+}
+
+func (n *transformInstruction) marshalText(w *textEncoder) (string, error) {
+	m := n.m
+	line := "transform"
+	for _, v := range []float64{m.XX, m.YX, m.XY, m.YY, m.X0, m.Y0} {
+		line += " " + formatFloat(v)
+	}
+	return line, nil
+}
+
+func (n *chanceInstruction) marshalText(w *textEncoder) (string, error) {
+	name, e := w.subprogramName(n.subprogram)
+	if e != nil {
+		return "", e
+	}
+	return "chance " + formatFloat(n.probability) + " " + name, nil
+}
+
+// Parses exactly count floating-point operands from the given fields.
+func parseOperands(fields []string, count int) ([]float64, error) {
+	if len(fields) != count {
+		return nil, fmt.Errorf("Expected %d operand(s), got %d", count,
+			len(fields))
+	}
+	toReturn := make([]float64, count)
+	for i, f := range fields {
+		v, e := strconv.ParseFloat(f, 64)
+		if e != nil {
+			return nil, fmt.Errorf("Invalid operand %q: %w", f, e)
+		}
+		toReturn[i] = v
+	}
+	return toReturn, nil
+}
+
+// Parses the operands of a single line of text, given the line's keyword. Each
+// parser receives the fields of the line following the keyword.
+type instructionParser func(d *textDecoder,
+	operands []string) (turtleInstruction, error)
+
+// Returns a parser for an instruction that takes count numeric operands.
+func numericParser(count int,
+	newInstruction func(v []float64) turtleInstruction) instructionParser {
+	return func(d *textDecoder, operands []string) (turtleInstruction,
+		error) {
+		v, e := parseOperands(operands, count)
+		if e != nil {
+			return nil, e
+		}
+		return newInstruction(v), nil
+	}
+}
+
+// Returns a parser for an instruction that takes no operands.
+func noOperandParser(
+	newInstruction func() turtleInstruction) instructionParser {
+	return numericParser(0, func(v []float64) turtleInstruction {
+		return newInstruction()
+	})
+}
+
+// Parses the operand of a "style" line.
+func parseStyle(d *textDecoder, operands []string) (turtleInstruction,
+	error) {
+	if len(operands) != 1 {
+		return nil, fmt.Errorf("Expected 1 operand, got %d", len(operands))
+	}
+	c, e := parseColor(operands[0])
+	if e != nil {
+		return nil, e
+	}
+	return &setStyleInstruction{style: GetColorStyle(c)}, nil
+}
+
+// Parses the operands of a "beginfill" line: a color and a fill rule.
+func parseBeginFill(d *textDecoder, operands []string) (turtleInstruction,
+	error) {
+	if len(operands) != 2 {
+		return nil, fmt.Errorf("Expected 2 operands, got %d", len(operands))
+	}
+	c, e := parseColor(operands[0])
+	if e != nil {
+		return nil, e
+	}
+	var rule FillRule
+	switch operands[1] {
+	case NonZeroFill.String():
+		rule = NonZeroFill
+	case EvenOddFill.String():
+		rule = EvenOddFill
+	default:
+		return nil, fmt.Errorf("Invalid fill rule %q: expected %s or %s",
+			operands[1], NonZeroFill, EvenOddFill)
+	}
+	return &beginFillInstruction{
+		style: GetColorStyle(c),
+		rule:  rule,
+	}, nil
+}
+
+// Parses the operands of a "chance" line: a probability and the name of a
+// subprogram.
+func parseChance(d *textDecoder, operands []string) (turtleInstruction,
+	error) {
+	if len(operands) != 2 {
+		return nil, fmt.Errorf("Expected 2 operands, got %d", len(operands))
+	}
+	v, e := parseOperands(operands[0:1], 1)
+	if e != nil {
+		return nil, e
+	}
+	subprogram, e := d.lookup(operands[1])
+	if e != nil {
+		return nil, e
+	}
+	return &chanceInstruction{
+		probability: v[0],
+		subprogram:  subprogram,
+	}, nil
+}
+
+// Parses the operands of a "call" line: a scale and the name of a subprogram.
+func parseCall(d *textDecoder, operands []string) (turtleInstruction,
+	error) {
+	if len(operands) != 2 {
+		return nil, fmt.Errorf("Expected 2 operands, got %d", len(operands))
+	}
+	v, e := parseOperands(operands[0:1], 1)
+	if e != nil {
+		return nil, e
+	}
+	sub, e := d.lookup(operands[1])
+	if e != nil {
+		return nil, e
+	}
+	return &callInstruction{
+		sub:   sub,
+		scale: v[0],
+	}, nil
+}
+
+// Parses the operands of a "callrecursive" line: an integer depth, a scale,
+// the name of the procedure and optionally the name of the base subprogram.
+func parseCallRecursive(d *textDecoder, operands []string) (turtleInstruction,
+	error) {
+	if (len(operands) != 3) && (len(operands) != 4) {
+		return nil, fmt.Errorf("Expected 3 or 4 operands, got %d",
+			len(operands))
+	}
+	depth, e := strconv.Atoi(operands[0])
+	if e != nil {
+		return nil, fmt.Errorf("Invalid depth %q: %w", operands[0], e)
+	}
+	v, e := parseOperands(operands[1:2], 1)
+	if e != nil {
+		return nil, e
+	}
+	p := &procedure{}
+	p.body, e = d.lookup(operands[2])
+	if e != nil {
+		return nil, e
+	}
+	if len(operands) == 4 {
+		p.base, e = d.lookup(operands[3])
+		if e != nil {
+			return nil, e
+		}
+	}
+	return &callRecursiveInstruction{
+		procedure: p,
+		depth:     depth,
+		scale:     v[0],
+	}, nil
+}
+
+// Parses the operand of a "stamp" line: the name of a subprogram.
+func parseStamp(d *textDecoder, operands []string) (turtleInstruction,
+	error) {
+	if len(operands) != 1 {
+		return nil, fmt.Errorf("Expected 1 operand, got %d", len(operands))
+	}
+	shape, e := d.lookup(operands[0])
+	if e != nil {
+		return nil, e
+	}
+	return &stampInstruction{
+		shape: shape,
+	}, nil
+}
+
+// Maps each keyword in the text format to the parser for its instruction.
+var instructionParsers = map[string]instructionParser{
+	"forward": numericParser(1, func(v []float64) turtleInstruction {
+		return &moveForwardInstruction{distance: v[0]}
+	}),
+	"turn": numericParser(1, func(v []float64) turtleInstruction {
+		return &turnInstruction{degrees: v[0]}
+	}),
+	"style": parseStyle,
+	"arc": numericParser(2, func(v []float64) turtleInstruction {
+		return &moveArcInstruction{radius: v[0], degrees: v[1]}
+	}),
+	"goto": numericParser(2, func(v []float64) turtleInstruction {
+		return &goToInstruction{x: v[0], y: v[1], draw: true}
+	}),
+	"jumpto": numericParser(2, func(v []float64) turtleInstruction {
+		return &goToInstruction{x: v[0], y: v[1], draw: false}
+	}),
+	"heading": numericParser(1, func(v []float64) turtleInstruction {
+		return &setHeadingInstruction{degrees: v[0]}
+	}),
+	"face": numericParser(2, func(v []float64) turtleInstruction {
+		return &faceTowardsInstruction{x: v[0], y: v[1]}
+	}),
+	"home": noOperandParser(func() turtleInstruction {
+		return &homeInstruction{}
+	}),
+	"penup": noOperandParser(func() turtleInstruction {
+		return &setPenInstruction{up: true}
+	}),
+	"pendown": noOperandParser(func() turtleInstruction {
+		return &setPenInstruction{up: false}
+	}),
+	"push": noOperandParser(func() turtleInstruction {
+		return &pushPositionInstruction{}
+	}),
+	"pop": noOperandParser(func() turtleInstruction {
+		return &popPositionInstruction{}
+	}),
+	"randomturn": numericParser(2, func(v []float64) turtleInstruction {
+		return &turnRandomInstruction{min: v[0], max: v[1]}
+	}),
+	"randomforward": numericParser(2, func(v []float64) turtleInstruction {
+		return &moveForwardRandomInstruction{min: v[0], max: v[1]}
+	}),
+	"quadratic": numericParser(4, func(v []float64) turtleInstruction {
+		n := &bezierInstruction{cubic: false}
+		copy(n.offsets[:], v)
+		return n
+	}),
+	"cubic": numericParser(6, func(v []float64) turtleInstruction {
+		n := &bezierInstruction{cubic: true}
+		copy(n.offsets[:], v)
+		return n
+	}),
+	"chance":    parseChance,
+	"beginfill": parseBeginFill,
+	"dot": numericParser(1, func(v []float64) turtleInstruction {
+		return &dotInstruction{diameter: v[0]}
+	}),
+	"stamp":         parseStamp,
+	"call":          parseCall,
+	"callrecursive": parseCallRecursive,
+	"recurse": numericParser(1, func(v []float64) turtleInstruction {
+		return &recurseInstruction{scale: v[0]}
+	}),
+	"transform": numericParser(6, func(v []float64) turtleInstruction {
+		return &transformInstruction{m: transformFromOperands(v)}
+	}),
+	"endfill": noOperandParser(func() turtleInstruction {
+		return &endFillInstruction{}
+	}),
+	"scalestep": numericParser(1, func(v []float64) turtleInstruction {
+		return &scaleStepInstruction{factor: v[0]}
+	}),
+	"width": numericParser(1, func(v []float64) turtleInstruction {
+		return &setLineWidthInstruction{width: v[0]}
+	}),
+	"scalewidth": numericParser(1, func(v []float64) turtleInstruction {
+		return &scaleLineWidthInstruction{factor: v[0]}
+	}),
+}
+
+// Returns a "start" line giving a turtle's starting position and heading, or
+// an empty string if the turtle starts at the origin facing 0 degrees.
+func formatStart(t *Turtle) string {
+	if t.start == (turtlePosition{}) {
+		return ""
+	}
+	return "start " + formatFloat(t.start.x) + " " + formatFloat(t.start.y) +
+		" " + formatFloat(t.start.angle) + "\n"
+}
+
+// Holds the state needed while converting a turtle to text.
+type textEncoder struct {
+	// The names of subprograms whose definitions have been written.
+	names map[*Turtle]string
+	// Subprograms whose definitions are currently being converted. Used to
+	// detect subprograms that contain themselves.
+	inProgress map[*Turtle]bool
+	// The definitions of all subprograms, each preceding any definitions
+	// that refer to it.
+	definitions bytes.Buffer
+}
+
+// Returns the name of the given subprogram, writing its definition first if
+// it hasn't already been written.
+func (w *textEncoder) subprogramName(t *Turtle) (string, error) {
+	name, ok := w.names[t]
+	if ok {
+		return name, nil
+	}
+	if w.inProgress[t] {
+		return "", fmt.Errorf("A subprogram contains itself")
+	}
+	w.inProgress[t] = true
+	defer delete(w.inProgress, t)
+	body, e := w.encodeInstructions(t.instructions.decodeAll())
+	if e != nil {
+		return "", e
+	}
+	name = fmt.Sprintf("s%d", len(w.names)+1)
+	w.definitions.WriteString("define " + name + " {\n")
+	w.definitions.WriteString(formatStart(t))
+	w.definitions.Write(body)
+	w.definitions.WriteString("}\n")
+	w.names[t] = name
+	return name, nil
+}
+
+// Converts a list of instructions to text, one per line.
+func (w *textEncoder) encodeInstructions(
+	instructions []Instruction) ([]byte, error) {
+	var b bytes.Buffer
+	for i, n := range instructions {
+		builtIn, ok := n.(turtleInstruction)
+		if !ok {
+			return nil, fmt.Errorf("Failed converting instruction %d/%d "+
+				"(%s) to text: user-defined instructions can't be "+
+				"converted to text", i+1, len(instructions), n.String())
+		}
+		line, e := builtIn.marshalText(w)
+		if e != nil {
+			return nil, fmt.Errorf("Failed converting instruction %d/%d "+
+				"(%s) to text: %w", i+1, len(instructions), n.String(), e)
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// Holds the state needed while parsing a turtle from text.
+type textDecoder struct {
+	// The subprograms that have been defined so far, keyed by name.
+	definitions map[string]*Turtle
+}
+
+// Returns the subprogram with the given name, which must already be defined.
+func (d *textDecoder) lookup(name string) (*Turtle, error) {
+	t := d.definitions[name]
+	if t == nil {
+		return nil, fmt.Errorf("Undefined subprogram %q", name)
+	}
+	return t, nil
+}
+
+// Parses a "text" line: a height followed by a Go-style quoted string, which
+// may contain spaces.
+func parseWriteText(line string) (turtleInstruction, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "text"))
+	end := strings.IndexAny(rest, " \t")
+	if end < 0 {
+		return nil, fmt.Errorf("Expected a height and a quoted string")
+	}
+	v, e := parseOperands([]string{rest[:end]}, 1)
+	if e != nil {
+		return nil, e
+	}
+	s, e := strconv.Unquote(strings.TrimSpace(rest[end:]))
+	if e != nil {
+		return nil, fmt.Errorf("Invalid quoted string: %w", e)
+	}
+	return &writeTextInstruction{
+		text:   s,
+		height: v[0],
+	}, nil
+}
+
+// Parses a single non-empty, non-comment line of the text format.
+func (d *textDecoder) parseInstruction(line string) (turtleInstruction,
+	error) {
+	fields := strings.Fields(line)
+	if fields[0] == "text" {
+		return parseWriteText(line)
+	}
+	parser := instructionParsers[fields[0]]
+	if parser == nil {
+		return nil, fmt.Errorf("Unknown instruction %q", fields[0])
+	}
+	return parser(d, fields[1:])
+}
+
+// Implements the encoding.TextMarshaler interface. Returns the turtle's
+// instructions in a line-oriented text format, with one instruction per line.
+// Subprograms used by instructions such as Chance are written as named
+// definitions before the instructions that use them:
+//
+//	define s1 {
+//	forward 1
+//	}
+//	chance 0.5 s1
+//
+// If the turtle's seed isn't 0, the text starts with a "seed" line. Turtles
+// and subprograms that don't start at the origin facing 0 degrees have a
+// "start x y heading" line, with the heading in degrees. Angles are always
+// written in degrees, counter-clockwise, regardless of the turtle's
+// TurtleOptions. Returns an error if the turtle uses a stroke style that
+// wasn't created by GetColorStyle, or contains instructions added using
+// Turtle.Add that aren't built in.
+func (t *Turtle) MarshalText() ([]byte, error) {
+	w := &textEncoder{
+		names:      make(map[*Turtle]string),
+		inProgress: map[*Turtle]bool{t: true},
+	}
+	body, e := w.encodeInstructions(t.instructions.decodeAll())
+	if e != nil {
+		return nil, e
+	}
+	var b bytes.Buffer
+	if t.seed != 0 {
+		b.WriteString("seed " + strconv.FormatInt(t.seed, 10) + "\n")
+	}
+	b.WriteString(formatStart(t))
+	b.Write(w.definitions.Bytes())
+	b.Write(body)
+	return b.Bytes(), nil
+}
+
+// Implements the encoding.TextUnmarshaler interface. Replaces the turtle's
+// instructions, seed and starting position with those parsed from text in the
+// format produced by MarshalText. The turtle's options are unchanged, and only
+// affect instructions added after this. The turtle is left unchanged if an
+// error occurs. Errors include the line number of the offending line.
+func (t *Turtle) UnmarshalText(text []byte) error {
+	d := &textDecoder{
+		definitions: make(map[string]*Turtle),
+	}
+	var seed int64
+	var start turtlePosition
+	instructions := newInstructionList(initialInstructionCapacity)
+	// Points to the instructions of the subprogram being defined, if any.
+	var definition *Turtle
+	definitionName := ""
+	definitionLine := 0
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if (line == "") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "define":
+			if definition != nil {
+				return fmt.Errorf("Line %d: Definitions can't be nested",
+					lineNumber)
+			}
+			if (len(fields) != 3) || (fields[2] != "{") {
+				return fmt.Errorf("Line %d: Expected \"define <name> {\"",
+					lineNumber)
+			}
+			if d.definitions[fields[1]] != nil {
+				return fmt.Errorf("Line %d: Subprogram %q is already "+
+					"defined", lineNumber, fields[1])
+			}
+			definition = NewTurtle()
+			definitionName = fields[1]
+			definitionLine = lineNumber
+			continue
+		case "}":
+			if definition == nil {
+				return fmt.Errorf("Line %d: Unexpected \"}\"", lineNumber)
+			}
+			d.definitions[definitionName] = definition
+			definition = nil
+			continue
+		case "seed":
+			if (definition != nil) || (len(fields) != 2) {
+				return fmt.Errorf("Line %d: Expected \"seed <integer>\" "+
+					"outside of any definition", lineNumber)
+			}
+			v, e := strconv.ParseInt(fields[1], 10, 64)
+			if e != nil {
+				return fmt.Errorf("Line %d: Invalid seed: %w", lineNumber, e)
+			}
+			seed = v
+			continue
+		case "start":
+			v, e := parseOperands(fields[1:], 3)
+			if e != nil {
+				return fmt.Errorf("Line %d: Invalid start: %w", lineNumber,
+					e)
+			}
+			p := turtlePosition{
+				x:     v[0],
+				y:     v[1],
+				angle: v[2],
+			}
+			if definition != nil {
+				definition.start = p
+			} else {
+				start = p
+			}
+			continue
+		}
+		n, e := d.parseInstruction(line)
+		if e != nil {
+			return fmt.Errorf("Line %d: %w", lineNumber, e)
+		}
+		if definition != nil {
+			definition.instructions.addInstruction(n)
+		} else {
+			instructions.addInstruction(n)
+		}
+	}
+	e := scanner.Err()
+	if e != nil {
+		return fmt.Errorf("Failed reading turtle text: %w", e)
+	}
+	if definition != nil {
+		return fmt.Errorf("Line %d: Definition of %q is missing \"}\"",
+			definitionLine, definitionName)
+	}
+	t.instructions = instructions
+	t.seed = seed
+	t.start = start
+	return nil
+}
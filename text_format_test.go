@@ -0,0 +1,194 @@
+package turtle_graphics
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// Returns the turtle's drawing as a PNG image.
+func getTurtlePNG(t *testing.T, turtle *Turtle) []byte {
+	var b bytes.Buffer
+	e := SaveTurtleAsPNG(turtle, 200, &b)
+	if e != nil {
+		t.Fatalf("Failed rendering turtle: %s", e)
+	}
+	return b.Bytes()
+}
+
+// Returns a turtle using subprograms, text, a seed and a starting position, so
+// that its text contains each kind of line.
+func getTextTestTurtle() *Turtle {
+	square := NewTurtleWithOptions(TurtleOptions{X: 0.5, Y: -0.5})
+	for i := 0; i < 4; i++ {
+		square.MoveForward(1)
+		square.Turn(90)
+	}
+	branch := NewTurtle()
+	branch.MoveForward(0.5)
+	branch.Stamp(square)
+	t := NewTurtleWithOptions(TurtleOptions{X: 1, Y: 2, Heading: 30})
+	t.SetSeed(1337)
+	t.SetStyle(GetColorStyle(color.NRGBA{0x12, 0x34, 0x56, 0x78}))
+	t.WriteText("Say \"hi\"\tto #1", 0.5)
+	t.Chance(0.5, branch)
+	t.Stamp(square)
+	t.BeginFill(GetColorStyle(color.Black), EvenOddFill)
+	t.MoveArc(1, 270)
+	t.EndFill()
+	t.CubicBezier(1, 1, 2, -1, 3, 0)
+	t.TurnRandom(-10, 10)
+	t.MoveForward(0.1)
+	return t
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	turtle := getTextTestTurtle()
+	text, e := turtle.MarshalText()
+	if e != nil {
+		t.Fatalf("Failed converting turtle to text: %s", e)
+	}
+	t.Logf("Turtle as text:\n%s", text)
+	expectedLines := []string{
+		"seed 1337",
+		"start 1 2 30",
+		"start 0.5 -0.5 0",
+		"define s1 {",
+		"define s2 {",
+		"stamp s1",
+		"chance 0.5 s2",
+		"style #12345678",
+		"text 0.5 \"Say \\\"hi\\\"\\tto #1\"",
+		"beginfill #000000 evenodd",
+	}
+	for _, line := range expectedLines {
+		if !bytes.Contains(text, []byte(line+"\n")) {
+			t.Errorf("The text didn't contain the line %q", line)
+		}
+	}
+	// The square is only defined once, although it's used twice.
+	if bytes.Count(text, []byte("define")) != 2 {
+		t.Errorf("Expected 2 definitions in the text")
+	}
+
+	parsed := NewTurtle()
+	e = parsed.UnmarshalText(text)
+	if e != nil {
+		t.Fatalf("Failed parsing turtle text: %s", e)
+	}
+	if parsed.Seed() != turtle.Seed() {
+		t.Errorf("Expected seed %d, got %d", turtle.Seed(), parsed.Seed())
+	}
+	if parsed.Len() != turtle.Len() {
+		t.Errorf("Expected %d instructions, got %d", turtle.Len(),
+			parsed.Len())
+	}
+	text2, e := parsed.MarshalText()
+	if e != nil {
+		t.Fatalf("Failed converting parsed turtle to text: %s", e)
+	}
+	if !bytes.Equal(text, text2) {
+		t.Errorf("The parsed turtle's text differs:\n%s", text2)
+	}
+	if !bytes.Equal(getTurtlePNG(t, turtle), getTurtlePNG(t, parsed)) {
+		t.Errorf("The parsed turtle rendered a different image")
+	}
+}
+
+func TestTextComments(t *testing.T) {
+	text := `# A comment before anything else.
+
+  # An indented comment.
+define s1 {
+	# A comment in a definition.
+	forward 1
+
+}
+forward 2
+	stamp s1
+`
+	turtle := NewTurtle()
+	e := turtle.UnmarshalText([]byte(text))
+	if e != nil {
+		t.Fatalf("Failed parsing turtle text: %s", e)
+	}
+	if turtle.Len() != 2 {
+		t.Fatalf("Expected 2 instructions, got %d", turtle.Len())
+	}
+	it := turtle.Instructions()
+	it.Next()
+	d := it.Descriptor()
+	if (d.Kind != KindMoveForward) || (d.Operands[0] != 2) {
+		t.Errorf("Expected to move forward 2, got %s", d.String())
+	}
+}
+
+func TestTextErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		// The start of the expected error message.
+		expected string
+	}{
+		{"forward 1\nfly 2\n", "Line 2: Unknown instruction \"fly\""},
+		{"forward\n", "Line 1: Expected 1 operand(s), got 0"},
+		{"\n\nturn x\n", "Line 3: Invalid operand \"x\""},
+		{"style red\n", "Line 1: Invalid color \"red\""},
+		{"beginfill #000000 odd\n", "Line 1: Invalid fill rule \"odd\""},
+		{"# Comment\nstamp s1\n", "Line 2: Undefined subprogram \"s1\""},
+		{"define s1 {\ndefine s2 {\n",
+			"Line 2: Definitions can't be nested"},
+		{"define s1 {\n}\ndefine s1 {\n}\n",
+			"Line 3: Subprogram \"s1\" is already defined"},
+		{"forward 1\n}\n", "Line 2: Unexpected \"}\""},
+		{"forward 1\ndefine s1 {\nforward 1\n",
+			"Line 2: Definition of \"s1\" is missing \"}\""},
+		{"define s1 {\nseed 3\n}\n",
+			"Line 2: Expected \"seed <integer>\""},
+		{"start 1 2\n", "Line 1: Invalid start"},
+		{"text 1 \"unterminated\n", "Line 1: Invalid quoted string"},
+	}
+	for _, test := range tests {
+		turtle := NewTurtle()
+		turtle.MoveForward(1)
+		e := turtle.UnmarshalText([]byte(test.text))
+		if e == nil {
+			t.Errorf("Didn't get an error parsing %q", test.text)
+			continue
+		}
+		t.Logf("Got expected error: %s", e)
+		if !strings.HasPrefix(e.Error(), test.expected) {
+			t.Errorf("Expected an error starting with %q, got %q",
+				test.expected, e)
+		}
+		if turtle.Len() != 1 {
+			t.Errorf("A failed parse changed the turtle")
+		}
+	}
+}
+
+func TestTextUnsupportedInstructions(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.MoveForward(1)
+	turtle.Add(&nopTestInstruction{})
+	_, e := turtle.MarshalText()
+	if e == nil {
+		t.Fatalf("Didn't get an error converting a user-defined " +
+			"instruction to text")
+	}
+	t.Logf("Got expected error: %s", e)
+	if !strings.Contains(e.Error(), "instruction 2/2") {
+		t.Errorf("The error didn't identify the instruction")
+	}
+}
+
+// A minimal user-defined instruction, which does nothing.
+type nopTestInstruction struct{}
+
+func (n *nopTestInstruction) Apply(s *TurtleState, c Canvas) error {
+	return nil
+}
+
+func (n *nopTestInstruction) String() string {
+	return "Do nothing"
+}
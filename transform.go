@@ -0,0 +1,162 @@
+package turtle_graphics
+
+// This file contains a canvas wrapper that transforms everything drawn to it,
+// used for drawing subprograms relative to the turtle and for drawing in a
+// turtle's transformed frame.
+
+import (
+	"math"
+)
+
+// Wraps another canvas, applying an affine transformation to everything drawn
+// to it.
+type transformCanvas struct {
+	canvas Canvas
+	// Converts points on this canvas to points on the underlying canvas.
+	m AffineTransform
+	// The factor by which line widths and the diameters of dots are scaled:
+	// the square root of the factor by which areas are scaled.
+	scale float64
+	// Set if the transformation only rotates, translates and uniformly
+	// scales, so that circles remain circles and can be passed to the
+	// underlying canvas's DrawArc.
+	similar bool
+	// The angle, in degrees, by which everything drawn is rotated. Only valid
+	// if similar is set.
+	angle float64
+}
+
+// Returns a canvas drawing to c, where the origin is at (x, y) on c, angle 0
+// points in the given direction on c, and a distance of 1 is the given scale
+// on c. A negative scale is equivalent to a positive one with the angle
+// rotated by 180 degrees. If c is itself a transformCanvas, the
+// transformations are combined rather than wrapping one inside the other.
+func newTransformCanvas(c Canvas, x, y, angle,
+	scale float64) *transformCanvas {
+	m := similarityTransform(x, y, angle, scale)
+	inner, ok := c.(*transformCanvas)
+	if ok {
+		m = inner.m.Multiply(m)
+		c = inner.canvas
+	}
+	toReturn := newAffineCanvas(c, m)
+	return &toReturn
+}
+
+// Returns a transformCanvas without an underlying canvas, which can only be
+// used to transform points and angles, with the same arguments as
+// newTransformCanvas.
+func newTransform(x, y, angle, scale float64) transformCanvas {
+	return newAffineCanvas(nil, similarityTransform(x, y, angle, scale))
+}
+
+// Returns a canvas drawing to c using the given transformation. Unlike
+// newTransformCanvas, never combines the transformation with c's.
+func newAffineCanvas(c Canvas, m AffineTransform) transformCanvas {
+	scale := math.Sqrt(math.Abs(m.determinant()))
+	// Allow for rounding errors in transformations built from rotations.
+	tolerance := scale * 1e-9
+	similar := (math.Abs(m.XX-m.YY) <= tolerance) &&
+		(math.Abs(m.XY+m.YX) <= tolerance)
+	return transformCanvas{
+		canvas:  c,
+		m:       m,
+		scale:   scale,
+		similar: similar,
+		angle:   math.Atan2(m.YX, m.XX) * 180.0 / math.Pi,
+	}
+}
+
+// Converts a point on this canvas to a point on the underlying canvas.
+func (c *transformCanvas) transformPoint(x, y float64) (float64, float64) {
+	return c.m.Apply(x, y)
+}
+
+// Converts a direction on this canvas, in degrees, and a distance in that
+// direction to the corresponding direction and distance on the underlying
+// canvas.
+func (c *transformCanvas) transformVector(angle,
+	length float64) (float64, float64) {
+	if c.similar {
+		return angle + c.angle, length * c.scale
+	}
+	dx, dy := moveDegrees(0, 0, angle, length)
+	dx, dy = c.m.applyLinear(dx, dy)
+	return math.Atan2(dy, dx) * 180.0 / math.Pi, math.Hypot(dx, dy)
+}
+
+// Converts a heading on this canvas to a heading on the underlying canvas.
+func (c *transformCanvas) transformAngle(angle float64) float64 {
+	angle, _ = c.transformVector(angle, 1)
+	return angle
+}
+
+// Scales the width of the style, if it has one.
+func (c *transformCanvas) SetStyle(s StrokeStyle) error {
+	widthStyle, ok := s.(WidthStrokeStyle)
+	if ok && (c.scale != 1) {
+		s = &widthStrokeStyle{
+			StrokeStyle: widthStyle,
+			width:       widthStyle.GetWidth() * c.scale,
+		}
+	}
+	return c.canvas.SetStyle(s)
+}
+
+func (c *transformCanvas) DrawLine(x, y, angle, length float64) error {
+	x, y = c.transformPoint(x, y)
+	angle, length = c.transformVector(angle, length)
+	return c.canvas.DrawLine(x, y, angle, length)
+}
+
+// Draws arcs directly if the transformation keeps them circular, and as
+// Bezier curves approximating the transformed arc otherwise.
+func (c *transformCanvas) DrawArc(x, y, angle, radius, degrees float64) error {
+	if c.similar {
+		x, y = c.transformPoint(x, y)
+		return c.canvas.DrawArc(x, y, angle+c.angle, radius*c.scale, degrees)
+	}
+	for _, curve := range arcToBeziers(x, y, angle, radius, degrees) {
+		for i := range curve {
+			curve[i].X, curve[i].Y = c.transformPoint(curve[i].X, curve[i].Y)
+		}
+		e := drawBezier(c.canvas, curve[0], curve[1], curve[2], curve[3])
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// Implements the FillCanvas interface. Does nothing if the underlying canvas
+// can't fill polygons.
+func (c *transformCanvas) FillPolygon(points []Point, style StrokeStyle,
+	rule FillRule) error {
+	fillCanvas, ok := c.canvas.(FillCanvas)
+	if !ok {
+		return nil
+	}
+	transformed := make([]Point, len(points))
+	for i, p := range points {
+		transformed[i].X, transformed[i].Y = c.transformPoint(p.X, p.Y)
+	}
+	return fillCanvas.FillPolygon(transformed, style, rule)
+}
+
+// Implements the BezierCanvas interface, falling back to line segments if the
+// underlying canvas doesn't support curves.
+func (c *transformCanvas) DrawBezier(p0, p1, p2, p3 Point) error {
+	points := []*Point{&p0, &p1, &p2, &p3}
+	for _, p := range points {
+		p.X, p.Y = c.transformPoint(p.X, p.Y)
+	}
+	return drawBezier(c.canvas, p0, p1, p2, p3)
+}
+
+// Implements the DotCanvas interface, falling back to a circle if the
+// underlying canvas doesn't support dots. Dots remain circular even if the
+// transformation doesn't scale uniformly.
+func (c *transformCanvas) DrawDot(x, y, diameter float64) error {
+	x, y = c.transformPoint(x, y)
+	return drawDot(c.canvas, x, y, diameter*c.scale)
+}
@@ -0,0 +1,483 @@
+package turtle_graphics
+
+// This file contains a turtle that moves in three dimensions, using the
+// heading, left and up vectors described in "The Algorithmic Beauty of
+// Plants". Its drawings are rendered to ordinary 2D canvases using a Camera.
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+)
+
+// A point or direction in three dimensions.
+type Vector3 struct {
+	X, Y, Z float64
+}
+
+// Returns v + w.
+func (v Vector3) Add(w Vector3) Vector3 {
+	return Vector3{v.X + w.X, v.Y + w.Y, v.Z + w.Z}
+}
+
+// Returns v - w.
+func (v Vector3) Sub(w Vector3) Vector3 {
+	return Vector3{v.X - w.X, v.Y - w.Y, v.Z - w.Z}
+}
+
+// Returns v multiplied by the scalar s.
+func (v Vector3) Scale(s float64) Vector3 {
+	return Vector3{v.X * s, v.Y * s, v.Z * s}
+}
+
+// Returns the dot product of v and w.
+func (v Vector3) Dot(w Vector3) float64 {
+	return v.X*w.X + v.Y*w.Y + v.Z*w.Z
+}
+
+// Returns the cross product of v and w.
+func (v Vector3) Cross(w Vector3) Vector3 {
+	return Vector3{
+		X: v.Y*w.Z - v.Z*w.Y,
+		Y: v.Z*w.X - v.X*w.Z,
+		Z: v.X*w.Y - v.Y*w.X,
+	}
+}
+
+// Returns the length of v.
+func (v Vector3) Length() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// Returns a vector with length 1 in the same direction as v. Returns the zero
+// vector if v has length 0.
+func (v Vector3) Normalize() Vector3 {
+	length := v.Length()
+	if length == 0 {
+		return Vector3{}
+	}
+	return v.Scale(1 / length)
+}
+
+// Holds the position and orientation of a 3D turtle, along with its pen state.
+// The heading, left and up vectors are always orthogonal unit vectors, with up
+// = heading x left.
+type turtlePosition3D struct {
+	position          Vector3
+	heading, left, up Vector3
+	penUp             bool
+	// The most recent style set by an instruction, or nil if none has been
+	// set, and an ID identifying the instruction that set it, as in
+	// turtlePosition.
+	style   StrokeStyle
+	styleID int
+}
+
+// Rotates the two given unit vectors by the given angle within the plane
+// they span, so that a moves towards b.
+func rotatePair(a, b Vector3, degrees float64) (Vector3, Vector3) {
+	radians := degrees * math.Pi / 180.0
+	cos := math.Cos(radians)
+	sin := math.Sin(radians)
+	return a.Scale(cos).Add(b.Scale(sin)), b.Scale(cos).Sub(a.Scale(sin))
+}
+
+// Re-orthonormalizes the turtle's orientation, to prevent rounding errors from
+// accumulating over many rotations.
+func (p *turtlePosition3D) orthonormalize() {
+	p.heading = p.heading.Normalize()
+	p.left = p.left.Sub(p.heading.Scale(p.left.Dot(p.heading))).Normalize()
+	p.up = p.heading.Cross(p.left)
+}
+
+// Receives the segments drawn by a 3D turtle while it is rendered.
+type segmentSink3D interface {
+	SetStyle(s StrokeStyle) error
+	drawSegment(a, b Vector3) error
+}
+
+// Holds the state of a 3D turtle while its instructions are carried out.
+type turtleState3D struct {
+	position      turtlePosition3D
+	positionStack []turtlePosition3D
+	sink          segmentSink3D
+	// The number of styles set so far, used to give each one a different
+	// styleID.
+	styleCount int
+}
+
+// An instruction recorded by a Turtle3D.
+type turtle3DInstruction interface {
+	apply(s *turtleState3D) error
+	String() string
+}
+
+type moveForward3DInstruction struct {
+	distance float64
+}
+
+func (n *moveForward3DInstruction) String() string {
+	return fmt.Sprintf("Move forward %f units", n.distance)
+}
+
+func (n *moveForward3DInstruction) apply(s *turtleState3D) error {
+	start := s.position.position
+	end := start.Add(s.position.heading.Scale(n.distance))
+	if !s.position.penUp {
+		e := s.sink.drawSegment(start, end)
+		if e != nil {
+			return e
+		}
+	}
+	s.position.position = end
+	return nil
+}
+
+// The axis a rotate3DInstruction rotates around.
+type rotationAxis int
+
+const (
+	yawAxis rotationAxis = iota
+	pitchAxis
+	rollAxis
+)
+
+type rotate3DInstruction struct {
+	axis    rotationAxis
+	degrees float64
+}
+
+func (n *rotate3DInstruction) String() string {
+	switch n.axis {
+	case yawAxis:
+		return fmt.Sprintf("Yaw %f degrees", n.degrees)
+	case pitchAxis:
+		return fmt.Sprintf("Pitch %f degrees", n.degrees)
+	}
+	return fmt.Sprintf("Roll %f degrees", n.degrees)
+}
+
+func (n *rotate3DInstruction) apply(s *turtleState3D) error {
+	p := &(s.position)
+	switch n.axis {
+	case yawAxis:
+		p.heading, p.left = rotatePair(p.heading, p.left, n.degrees)
+	case pitchAxis:
+		p.heading, p.up = rotatePair(p.heading, p.up, n.degrees)
+	case rollAxis:
+		p.left, p.up = rotatePair(p.left, p.up, n.degrees)
+	}
+	p.orthonormalize()
+	return nil
+}
+
+type setPen3DInstruction struct {
+	up bool
+}
+
+func (n *setPen3DInstruction) String() string {
+	if n.up {
+		return "Pen up"
+	}
+	return "Pen down"
+}
+
+func (n *setPen3DInstruction) apply(s *turtleState3D) error {
+	s.position.penUp = n.up
+	return nil
+}
+
+type setStyle3DInstruction struct {
+	style StrokeStyle
+}
+
+func (n *setStyle3DInstruction) String() string {
+	return fmt.Sprintf("Set style %v", n.style)
+}
+
+func (n *setStyle3DInstruction) apply(s *turtleState3D) error {
+	s.styleCount++
+	s.position.style = n.style
+	s.position.styleID = s.styleCount
+	return s.sink.SetStyle(n.style)
+}
+
+type pushPosition3DInstruction struct{}
+
+func (n *pushPosition3DInstruction) String() string {
+	return "Push position"
+}
+
+func (n *pushPosition3DInstruction) apply(s *turtleState3D) error {
+	s.positionStack = append(s.positionStack, s.position)
+	return nil
+}
+
+type popPosition3DInstruction struct{}
+
+func (n *popPosition3DInstruction) String() string {
+	return "Pop position"
+}
+
+func (n *popPosition3DInstruction) apply(s *turtleState3D) error {
+	if len(s.positionStack) == 0 {
+		return ErrEmptyStack
+	}
+	topIndex := len(s.positionStack) - 1
+	styleChanged := s.positionStack[topIndex].styleID != s.position.styleID
+	s.position = s.positionStack[topIndex]
+	s.positionStack = s.positionStack[0:topIndex]
+	if !styleChanged {
+		return nil
+	}
+	style := s.position.style
+	if style == nil {
+		style = GetColorStyle(color.Black)
+	}
+	return s.sink.SetStyle(style)
+}
+
+// A turtle that moves in three dimensions. Like Turtle, it only records
+// instructions, which are carried out each time it's rendered. The turtle
+// starts at the origin with its heading along the positive X axis, its left
+// along the positive Y axis and its up direction along the positive Z axis,
+// so a drawing that never leaves the XY plane matches the equivalent 2D
+// Turtle's drawing when viewed from above.
+type Turtle3D struct {
+	instructions []turtle3DInstruction
+}
+
+// Returns an initialized Turtle3D instance, with no instructions.
+func NewTurtle3D() *Turtle3D {
+	return &Turtle3D{
+		instructions: make([]turtle3DInstruction, 0,
+			initialInstructionCapacity),
+	}
+}
+
+// Adds an instruction to move forward by the given distance along the
+// turtle's heading, drawing a line if the pen is down.
+func (t *Turtle3D) MoveForward(distance float64) {
+	n := &moveForward3DInstruction{
+		distance: distance,
+	}
+	t.instructions = append(t.instructions, n)
+}
+
+// Adds an instruction to turn left by the given number of degrees, rotating
+// around the turtle's up direction. Negative angles turn right. This
+// corresponds to the L-system symbols + and -.
+func (t *Turtle3D) Yaw(degrees float64) {
+	n := &rotate3DInstruction{
+		axis:    yawAxis,
+		degrees: degrees,
+	}
+	t.instructions = append(t.instructions, n)
+}
+
+// Adds an instruction to pitch up by the given number of degrees, rotating
+// around the turtle's left direction. Negative angles pitch down. This
+// corresponds to the L-system symbols ^ and &.
+func (t *Turtle3D) Pitch(degrees float64) {
+	n := &rotate3DInstruction{
+		axis:    pitchAxis,
+		degrees: degrees,
+	}
+	t.instructions = append(t.instructions, n)
+}
+
+// Adds an instruction to roll by the given number of degrees, rotating around
+// the turtle's heading. Positive angles roll to the right, raising the
+// turtle's left side, and negative angles roll to the left. This corresponds
+// to the L-system symbols / and \.
+func (t *Turtle3D) Roll(degrees float64) {
+	n := &rotate3DInstruction{
+		axis:    rollAxis,
+		degrees: degrees,
+	}
+	t.instructions = append(t.instructions, n)
+}
+
+// Adds an instruction to turn the turtle around, so it faces the opposite
+// direction. This corresponds to the L-system symbol |.
+func (t *Turtle3D) TurnAround() {
+	t.Yaw(180)
+}
+
+// Adds an instruction to lift the turtle's pen, so subsequent moves don't
+// draw anything until PenDown is called.
+func (t *Turtle3D) PenUp() {
+	t.instructions = append(t.instructions, &setPen3DInstruction{up: true})
+}
+
+// Adds an instruction to lower the turtle's pen, so that subsequent moves are
+// drawn. The turtle's pen starts out down.
+func (t *Turtle3D) PenDown() {
+	t.instructions = append(t.instructions, &setPen3DInstruction{up: false})
+}
+
+// Adds an instruction to change the stroke style used for subsequent lines.
+// The style is saved by PushPosition and restored by PopPosition.
+func (t *Turtle3D) SetStyle(style StrokeStyle) {
+	n := &setStyle3DInstruction{
+		style: style,
+	}
+	t.instructions = append(t.instructions, n)
+}
+
+// Adds an instruction to push the turtle's position, orientation and pen state,
+// including its style, onto a stack.
+func (t *Turtle3D) PushPosition() {
+	t.instructions = append(t.instructions, &pushPosition3DInstruction{})
+}
+
+// Adds an instruction to restore the position, orientation and pen state on
+// top of the stack, removing it from the stack. If the style changed since the
+// position was pushed, the style is passed to the canvas again, or black is
+// used if no style had been set.
+func (t *Turtle3D) PopPosition() {
+	t.instructions = append(t.instructions, &popPosition3DInstruction{})
+}
+
+// Carries out the turtle's instructions, sending the lines it draws to the
+// given sink.
+func (t *Turtle3D) render(sink segmentSink3D) error {
+	s := &turtleState3D{
+		position: turtlePosition3D{
+			heading: Vector3{1, 0, 0},
+			left:    Vector3{0, 1, 0},
+			up:      Vector3{0, 0, 1},
+		},
+		positionStack: make([]turtlePosition3D, 0, 128),
+		sink:          sink,
+	}
+	for i, n := range t.instructions {
+		e := n.apply(s)
+		if e != nil {
+			return fmt.Errorf("Error executing instruction %d/%d (%s): %w",
+				i+1, len(t.instructions), n.String(), e)
+		}
+	}
+	return nil
+}
+
+// Projects the segments drawn by a 3D turtle onto a 2D canvas.
+type projectingSink struct {
+	canvas Canvas
+	camera Camera
+}
+
+func (p *projectingSink) SetStyle(s StrokeStyle) error {
+	return p.canvas.SetStyle(s)
+}
+
+func (p *projectingSink) drawSegment(a, b Vector3) error {
+	start, end, visible := p.camera.ProjectSegment(a, b)
+	if !visible {
+		return nil
+	}
+	dx := end.X - start.X
+	dy := end.Y - start.Y
+	return p.canvas.DrawLine(start.X, start.Y,
+		math.Atan2(dy, dx)*180.0/math.Pi, math.Hypot(dx, dy))
+}
+
+// Carries out all of the turtle's instructions, drawing the lines it traces
+// to the given 2D canvas after projecting them using the camera. Like
+// Turtle.RenderToCanvas, this may be called concurrently with different
+// canvases.
+func (t *Turtle3D) RenderToCanvas(c Canvas, camera Camera) error {
+	return t.render(&projectingSink{
+		canvas: c,
+		camera: camera,
+	})
+}
+
+// A connected sequence of line segments drawn by a 3D turtle, all using the
+// same stroke style.
+type Path3D struct {
+	// The vertices of the path, in order. Always contains at least two
+	// points.
+	Points []Vector3
+	// The style that was active when the path was drawn. nil if the path was
+	// drawn before any SetStyle instruction, and black if it was drawn after
+	// popping a position pushed before any SetStyle instruction.
+	Style StrokeStyle
+}
+
+// Collects the segments drawn by a 3D turtle into paths, in the same way as
+// pathCanvas.
+type pathSink3D struct {
+	style StrokeStyle
+	// Set when the style changes, so the next segment starts a new path.
+	styleChanged bool
+	paths        []Path3D
+}
+
+func (p *pathSink3D) SetStyle(s StrokeStyle) error {
+	p.style = s
+	p.styleChanged = true
+	return nil
+}
+
+func (p *pathSink3D) drawSegment(a, b Vector3) error {
+	if !p.styleChanged && (len(p.paths) != 0) {
+		current := &(p.paths[len(p.paths)-1])
+		if current.Points[len(current.Points)-1] == a {
+			current.Points = append(current.Points, b)
+			return nil
+		}
+	}
+	p.styleChanged = false
+	p.paths = append(p.paths, Path3D{
+		Points: []Vector3{a, b},
+		Style:  p.style,
+	})
+	return nil
+}
+
+// Returns the lines the turtle draws as a list of 3D polylines. Consecutive
+// segments are joined into a single path if each starts where the previous
+// one ended and the style didn't change between them.
+func (t *Turtle3D) Paths() ([]Path3D, error) {
+	sink := &pathSink3D{
+		paths: make([]Path3D, 0, 64),
+	}
+	e := t.render(sink)
+	if e != nil {
+		return nil, fmt.Errorf("Failed tracing the turtle's paths: %w", e)
+	}
+	return sink.paths, nil
+}
+
+// Writes the lines the turtle draws to the given writer as a Wavefront OBJ
+// file, containing a vertex for each point and a polyline ("l") element for
+// each path returned by Paths. Styles aren't included.
+func (t *Turtle3D) WriteOBJ(out io.Writer) error {
+	paths, e := t.Paths()
+	if e != nil {
+		return e
+	}
+	w := bufio.NewWriter(out)
+	vertexCount := 0
+	for _, path := range paths {
+		for _, p := range path.Points {
+			fmt.Fprintf(w, "v %s %s %s\n", formatFloat(p.X),
+				formatFloat(p.Y), formatFloat(p.Z))
+		}
+		w.WriteString("l")
+		for i := range path.Points {
+			// OBJ vertex indices start at 1.
+			fmt.Fprintf(w, " %d", vertexCount+i+1)
+		}
+		w.WriteString("\n")
+		vertexCount += len(path.Points)
+	}
+	e = w.Flush()
+	if e != nil {
+		return fmt.Errorf("Failed writing OBJ file: %w", e)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package turtle_graphics
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Returns the 3D turtle's paths, failing the test on error.
+func getTestPaths3D(t *testing.T, turtle *Turtle3D) []Path3D {
+	paths, e := turtle.Paths()
+	if e != nil {
+		t.Fatalf("Failed getting 3D paths: %s", e)
+	}
+	return paths
+}
+
+// Returns true if the two points are within 1e-9 of each other.
+func closeVectors(a, b Vector3) bool {
+	return a.Sub(b).Length() < 1e-9
+}
+
+// Checks that the 3D turtle draws the expected polylines, within 1e-9.
+func checkPaths3D(t *testing.T, name string, turtle *Turtle3D,
+	expected [][]Vector3) {
+	paths := getTestPaths3D(t, turtle)
+	if len(paths) != len(expected) {
+		t.Errorf("%s: expected %d paths, got %d: %v", name, len(expected),
+			len(paths), paths)
+		return
+	}
+	for i, path := range paths {
+		if len(path.Points) != len(expected[i]) {
+			t.Errorf("%s: expected %d points in path %d, got %v", name,
+				len(expected[i]), i, path.Points)
+			continue
+		}
+		for j, p := range path.Points {
+			if !closeVectors(p, expected[i][j]) {
+				t.Errorf("%s: expected point %d of path %d to be %v, got %v",
+					name, j, i, expected[i][j], p)
+			}
+		}
+	}
+}
+
+func TestTurtle3DRotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(t *Turtle3D)
+		expected [][]Vector3
+	}{
+		{"Yaw", func(t *Turtle3D) {
+			t.MoveForward(1)
+			t.Yaw(90)
+			t.MoveForward(1)
+			t.Yaw(-90)
+			t.MoveForward(1)
+		}, [][]Vector3{{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {2, 1, 0}}}},
+		{"Pitch", func(t *Turtle3D) {
+			t.Pitch(90)
+			t.MoveForward(1)
+			t.Pitch(-90)
+			t.MoveForward(1)
+			t.Pitch(-90)
+			t.MoveForward(2)
+		}, [][]Vector3{{{0, 0, 0}, {0, 0, 1}, {1, 0, 1}, {1, 0, -1}}}},
+		// Rolling right raises the turtle's left side, so a left turn
+		// afterwards goes up.
+		{"Roll", func(t *Turtle3D) {
+			t.Roll(90)
+			t.Yaw(90)
+			t.MoveForward(1)
+			t.Roll(-90)
+			t.Pitch(90)
+			t.MoveForward(1)
+		}, [][]Vector3{{{0, 0, 0}, {0, 0, 1}, {-1, 0, 1}}}},
+		{"Turn around", func(t *Turtle3D) {
+			t.Pitch(45)
+			t.MoveForward(math.Sqrt2)
+			t.TurnAround()
+			t.MoveForward(2 * math.Sqrt2)
+		}, [][]Vector3{{{0, 0, 0}, {1, 0, 1}, {-1, 0, -1}}}},
+		{"Push and pop", func(t *Turtle3D) {
+			t.PushPosition()
+			t.Pitch(90)
+			t.PenUp()
+			t.MoveForward(1)
+			t.PopPosition()
+			t.MoveForward(1)
+			t.PushPosition()
+			t.Yaw(90)
+			t.MoveForward(1)
+			t.PopPosition()
+			t.Pitch(-90)
+			t.MoveForward(1)
+		}, [][]Vector3{{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}},
+			{{1, 0, 0}, {1, 0, -1}}}},
+	}
+	for _, test := range tests {
+		turtle := NewTurtle3D()
+		test.build(turtle)
+		checkPaths3D(t, test.name, turtle, test.expected)
+	}
+}
+
+func TestTurtle3DManyRotations(t *testing.T) {
+	// Rounding errors mustn't change the length of moves, even after many
+	// rotations.
+	r := rand.New(rand.NewSource(1337))
+	turtle := NewTurtle3D()
+	for i := 0; i < 10000; i++ {
+		switch r.Intn(3) {
+		case 0:
+			turtle.Yaw(r.Float64() * 360)
+		case 1:
+			turtle.Pitch(r.Float64() * 360)
+		case 2:
+			turtle.Roll(r.Float64() * 360)
+		}
+		turtle.MoveForward(1)
+	}
+	for _, path := range getTestPaths3D(t, turtle) {
+		for i := 1; i < len(path.Points); i++ {
+			length := path.Points[i].Sub(path.Points[i-1]).Length()
+			if math.Abs(length-1) > 1e-9 {
+				t.Fatalf("Expected a move of length 1, got %f", length)
+			}
+		}
+	}
+}
+
+// Returns the 2D paths drawn by projecting the 3D turtle using the camera.
+func getProjectedPaths(t *testing.T, turtle *Turtle3D, camera Camera) []Path {
+	c := &pathCanvas{
+		tolerance: 0.01,
+	}
+	e := turtle.RenderToCanvas(c, camera)
+	if e != nil {
+		t.Fatalf("Failed rendering 3D turtle: %s", e)
+	}
+	return c.paths
+}
+
+func TestTurtle3DProjection(t *testing.T) {
+	// Draws a line along the X axis, and another farther from the camera.
+	turtle := NewTurtle3D()
+	turtle.MoveForward(1)
+	turtle.PenUp()
+	turtle.TurnAround()
+	turtle.MoveForward(1)
+	turtle.TurnAround()
+	turtle.Pitch(-90)
+	turtle.MoveForward(10)
+	turtle.Pitch(90)
+	turtle.PenDown()
+	turtle.MoveForward(1)
+	eye := Vector3{0, 0, 10}
+	up := Vector3{0, 1, 0}
+	orthographic, e := NewOrthographicCamera(eye, Vector3{}, up)
+	if e != nil {
+		t.Fatalf("Failed creating orthographic camera: %s", e)
+	}
+	// Lines keep their length regardless of their distance.
+	paths := getProjectedPaths(t, turtle, orthographic)
+	if len(paths) != 2 {
+		t.Fatalf("Expected 2 projected paths, got %d", len(paths))
+	}
+	checkPathPoints(t, paths[0], []Point{{0, 0}, {1, 0}})
+	checkPathPoints(t, paths[1], []Point{{0, 0}, {1, 0}})
+
+	// The 90 degree field of view puts the edges of the view at 45 degrees,
+	// so points are divided by their depth.
+	perspective, e := NewPerspectiveCamera(eye, Vector3{}, up, 90)
+	if e != nil {
+		t.Fatalf("Failed creating perspective camera: %s", e)
+	}
+	paths = getProjectedPaths(t, turtle, perspective)
+	if len(paths) != 2 {
+		t.Fatalf("Expected 2 projected paths, got %d", len(paths))
+	}
+	checkPathPoints(t, paths[0], []Point{{0, 0}, {0.1, 0}})
+	checkPathPoints(t, paths[1], []Point{{0, 0}, {0.05, 0}})
+
+	// Lines are clipped where they pass behind the camera.
+	turtle = NewTurtle3D()
+	turtle.Yaw(90)
+	turtle.MoveForward(1)
+	turtle.Yaw(-90)
+	turtle.Pitch(90)
+	turtle.MoveForward(20)
+	turtle.MoveForward(1)
+	paths = getProjectedPaths(t, turtle, perspective)
+	if len(paths) != 2 {
+		t.Fatalf("Expected 2 projected paths, got %d", len(paths))
+	}
+	checkPathPoints(t, paths[0], []Point{{0, 0}, {0, 0.1}})
+	// The second line is clipped at the near plane, DefaultNearPlane units
+	// in front of the camera.
+	checkPathPoints(t, paths[1], []Point{{0, 0.1}, {0, 1 / DefaultNearPlane}})
+}
+
+func TestCameraErrors(t *testing.T) {
+	up := Vector3{0, 0, 1}
+	_, e := NewOrthographicCamera(Vector3{1, 2, 3}, Vector3{1, 2, 3}, up)
+	if e == nil {
+		t.Errorf("Didn't get an error for a camera facing its own position")
+	} else {
+		t.Logf("Got expected error: %s", e)
+	}
+	_, e = NewOrthographicCamera(Vector3{0, 0, 5}, Vector3{}, up)
+	if e == nil {
+		t.Errorf("Didn't get an error for a camera facing its up direction")
+	} else {
+		t.Logf("Got expected error: %s", e)
+	}
+	for _, fieldOfView := range []float64{0, 180, -10, math.NaN()} {
+		_, e = NewPerspectiveCamera(Vector3{5, 0, 0}, Vector3{}, up,
+			fieldOfView)
+		if e == nil {
+			t.Errorf("Didn't get an error for a field of view of %f",
+				fieldOfView)
+		} else {
+			t.Logf("Got expected error: %s", e)
+		}
+	}
+}
+
+func TestTurtle3DOBJ(t *testing.T) {
+	turtle := NewTurtle3D()
+	turtle.MoveForward(1)
+	turtle.Pitch(90)
+	turtle.MoveForward(0.5)
+	turtle.PenUp()
+	turtle.MoveForward(1)
+	turtle.PenDown()
+	turtle.Pitch(-90)
+	turtle.MoveForward(-2)
+	var b bytes.Buffer
+	e := turtle.WriteOBJ(&b)
+	if e != nil {
+		t.Fatalf("Failed writing OBJ file: %s", e)
+	}
+	expected := "v 0 0 0\nv 1 0 0\nv 1 0 0.5\nl 1 2 3\n" +
+		"v 1 0 1.5\nv -1 0 1.5\nl 4 5\n"
+	if b.String() != expected {
+		t.Errorf("Expected OBJ file:\n%s\nGot:\n%s", expected, b.String())
+	}
+}
@@ -8,9 +8,11 @@
 package turtle_graphics
 
 import (
+	"context"
 	"fmt"
 	"image/color"
 	"math"
+	"math/rand"
 )
 
 // The base interface for setting the style of the line to draw. Different
@@ -61,11 +63,15 @@ type Canvas interface {
 // is actually drawn, allowing a resulting bitmap rasterization to be properly
 // scaled. After drawing an image, call GetExtents to get the boundaries of the
 // rectangle. The bounds may not be exact, depending on styles and arcs used.
+// Since a turtle with its pen up doesn't draw anything, moves made with the pen
+// up do not contribute to the extents.
 type DummyCanvas struct {
 	minX, maxX, minY, maxY float64
 	// Needed so the initial values of 0 don't cause us to miss a proper
 	// minimum or maximum, since bounds can be negative.
 	initialized bool
+	// The width of lines, if the current style is a WidthStrokeStyle.
+	lineWidth float64
 }
 
 // Returns a new dummy canvas.
@@ -76,6 +82,7 @@ func NewDummyCanvas() *DummyCanvas {
 		minY:        0,
 		maxY:        0,
 		initialized: false,
+		lineWidth:   0,
 	}
 }
 
@@ -91,8 +98,13 @@ func (c *DummyCanvas) GetExtents() (minX, minY, maxX, maxY float64) {
 	return
 }
 
+// Only records the line width, if any, so that the extents contain the full
+// width of each line.
 func (c *DummyCanvas) SetStyle(s StrokeStyle) error {
-	// This is a no-op for the DummyCanvas.
+	c.lineWidth = 0
+	if widthStyle, ok := s.(WidthStrokeStyle); ok {
+		c.lineWidth = widthStyle.GetWidth()
+	}
 	return nil
 }
 
@@ -131,12 +143,43 @@ func moveDegrees(x, y, angle, distance float64) (float64, float64) {
 	return x, y
 }
 
+// Updates the extents to contain a square centered on (x, y) with sides as
+// long as the current line width.
+func (c *DummyCanvas) updateLineBounds(x, y float64) {
+	if c.lineWidth <= 0 {
+		c.updateBounds(x, y)
+		return
+	}
+	halfWidth := c.lineWidth / 2
+	c.updateBounds(x-halfWidth, y-halfWidth)
+	c.updateBounds(x+halfWidth, y+halfWidth)
+}
+
 func (c *DummyCanvas) DrawLine(x, y, angle, distance float64) error {
 	// Update the bounds based on the start point.
-	c.updateBounds(x, y)
+	c.updateLineBounds(x, y)
 	// Update the bounds based on the end point.
 	x, y = moveDegrees(x, y, angle, distance)
-	c.updateBounds(x, y)
+	c.updateLineBounds(x, y)
+	return nil
+}
+
+// Implements the FillCanvas interface. Ensures the extents contain every
+// vertex of the polygon.
+func (c *DummyCanvas) FillPolygon(points []Point, style StrokeStyle,
+	rule FillRule) error {
+	for _, p := range points {
+		c.updateBounds(p.X, p.Y)
+	}
+	return nil
+}
+
+// Implements the DotCanvas interface. Ensures the extents contain the entire
+// dot.
+func (c *DummyCanvas) DrawDot(x, y, diameter float64) error {
+	radius := math.Abs(diameter) / 2
+	c.updateBounds(x-radius, y-radius)
+	c.updateBounds(x+radius, y+radius)
 	return nil
 }
 
@@ -145,22 +188,36 @@ func (c *DummyCanvas) DrawArc(x, y, angle, radius, degrees float64) error {
 	// Rather than trying to do this specifically, we'll just treat this as if
 	// the entire circle must be in bounds. A tighter solution should look at
 	// endpoints and the edges instead.
-	c.updateBounds(centerX, centerY+radius)
-	c.updateBounds(centerX, centerY-radius)
-	c.updateBounds(centerX+radius, centerY)
-	c.updateBounds(centerX-radius, centerY)
+	c.updateLineBounds(centerX, centerY+radius)
+	c.updateLineBounds(centerX, centerY-radius)
+	c.updateLineBounds(centerX+radius, centerY)
+	c.updateLineBounds(centerX-radius, centerY)
 	return nil
 }
 
 // An "instruction" that manipulates the turtle's state. This uses an interface
-// to allow storing a list of all instructions that can be replayed.
-type turtleInstruction interface {
-	// Carries out the instruction, using the turtle and the given canvas.
-	apply(t *Turtle, c Canvas) error
+// to allow storing a list of all instructions that can be replayed. Users may
+// implement this interface to define their own instructions, and add them to a
+// turtle using Turtle.Add.
+type Instruction interface {
+	// Carries out the instruction, updating the turtle's state and drawing to
+	// the given canvas as needed.
+	Apply(s *TurtleState, c Canvas) error
 	// Returns a string representation of the instruction.
 	String() string
 }
 
+// The interface satisfied by all of the built-in instructions.
+type turtleInstruction interface {
+	Instruction
+	// Returns the instruction as a single line in the text format used by
+	// Turtle.MarshalText, without a trailing newline. See text_format.go.
+	marshalText(w *textEncoder) (string, error)
+	// Fills in the fields of the descriptor other than Instruction. See
+	// instruction_list.go.
+	describe(d *InstructionDescriptor)
+}
+
 // An instruction telling the turtle to move forward a certain amount.
 type moveForwardInstruction struct {
 	distance float64
@@ -170,16 +227,19 @@ func (n *moveForwardInstruction) String() string {
 	return fmt.Sprintf("Move forward by %f units", n.distance)
 }
 
-func (n *moveForwardInstruction) apply(t *Turtle, c Canvas) error {
-	x, y, angle := t.getPosition()
-	e := c.DrawLine(x, y, angle, n.distance)
-	if e != nil {
-		return fmt.Errorf("Failed applying move-forward instruction: %w", e)
+func (n *moveForwardInstruction) Apply(s *TurtleState, c Canvas) error {
+	x, y, angle := s.getPosition()
+	distance := n.distance * s.position.stepScale
+	if !s.position.penUp {
+		e := c.DrawLine(x, y, angle, distance)
+		if e != nil {
+			return fmt.Errorf("Failed applying move-forward instruction: %w",
+				e)
+		}
 	}
 	// Update the turtle's position (moving forward won't change its angle)
-	x, y = moveDegrees(x, y, angle, n.distance)
-	t.position.x = x
-	t.position.y = y
+	x, y = moveDegrees(x, y, angle, distance)
+	s.moveTo(x, y)
 	return nil
 }
 
@@ -193,15 +253,15 @@ func (n *turnInstruction) String() string {
 	return fmt.Sprintf("Turn by %f degrees", n.degrees)
 }
 
-func (n *turnInstruction) apply(t *Turtle, c Canvas) error {
-	angle := n.degrees + t.position.angle
+func (n *turnInstruction) Apply(s *TurtleState, c Canvas) error {
+	angle := n.degrees + s.position.angle
 	angle = math.Mod(angle, 360.0)
-	t.position.angle = angle
+	s.position.angle = angle
 	return nil
 }
 
-// Instructs the turtle to change the style of the line it's drawing. Doesn't
-// change the turtle's state; only affects the canvas.
+// Instructs the turtle to change the style of the line it's drawing. The style
+// is part of the turtle's position, so it's restored by PopPosition.
 type setStyleInstruction struct {
 	style StrokeStyle
 }
@@ -210,8 +270,14 @@ func (n *setStyleInstruction) String() string {
 	return "Set style"
 }
 
-func (n *setStyleInstruction) apply(t *Turtle, c Canvas) error {
-	return c.SetStyle(n.style)
+func (n *setStyleInstruction) Apply(s *TurtleState, c Canvas) error {
+	s.styleCount++
+	s.position.style = n.style
+	s.position.styleID = s.styleCount
+	if s.position.lineWidth <= 0 {
+		return c.SetStyle(n.style)
+	}
+	return c.SetStyle(s.canvasStyle())
 }
 
 // An instruction telling the turtle to draw an arc. Changes the turtle's
@@ -230,11 +296,14 @@ func (n *moveArcInstruction) String() string {
 		n.radius)
 }
 
-func (n *moveArcInstruction) apply(t *Turtle, c Canvas) error {
-	x, y, angle := t.getPosition()
-	e := c.DrawArc(x, y, angle, n.radius, n.degrees)
-	if e != nil {
-		return e
+func (n *moveArcInstruction) Apply(s *TurtleState, c Canvas) error {
+	x, y, angle := s.getPosition()
+	radius := n.radius * s.position.stepScale
+	if !s.position.penUp {
+		e := c.DrawArc(x, y, angle, radius, n.degrees)
+		if e != nil {
+			return e
+		}
 	}
 	// angle - 90 = the turtle's original position around the circle (if you
 	// add 90 degrees to face the center of the circle, you can subtract 90
@@ -245,13 +314,129 @@ func (n *moveArcInstruction) apply(t *Turtle, c Canvas) error {
 	// radius from the circle's center, in the direction of its new angle along
 	// the circle. Its new global angle is simply its old angle plus the
 	// degrees it traveled along the circle.
-	centerX, centerY := moveDegrees(x, y, angle+90.0, n.radius)
+	centerX, centerY := moveDegrees(x, y, angle+90.0, radius)
 	newX, newY := moveDegrees(centerX, centerY, n.degrees+(angle-90.0),
-		n.radius)
+		radius)
 	newAngle := math.Mod(angle+n.degrees, 360.0)
-	t.position.x = newX
-	t.position.y = newY
-	t.position.angle = newAngle
+	if s.fill != nil {
+		s.fill.addArc(s.position.transform, x, y, angle, radius, n.degrees)
+	}
+	s.moveTo(newX, newY)
+	s.position.angle = newAngle
+	return nil
+}
+
+// Normalizes an angle in degrees to the range [0, 360).
+func normalizeDegrees(angle float64) float64 {
+	angle = math.Mod(angle, 360.0)
+	if angle < 0 {
+		angle += 360.0
+	}
+	return angle
+}
+
+// An instruction telling the turtle to move in a straight line to an absolute
+// position, without changing the direction it's facing. If draw is false, the
+// turtle never draws a line, even if its pen is down.
+type goToInstruction struct {
+	x, y float64
+	draw bool
+}
+
+func (n *goToInstruction) String() string {
+	if n.draw {
+		return fmt.Sprintf("Go to (%f, %f)", n.x, n.y)
+	}
+	return fmt.Sprintf("Jump to (%f, %f)", n.x, n.y)
+}
+
+func (n *goToInstruction) Apply(s *TurtleState, c Canvas) error {
+	x, y, _ := s.getPosition()
+	if n.draw && !s.position.penUp {
+		dx := n.x - x
+		dy := n.y - y
+		lineAngle := math.Atan2(dy, dx) * 180.0 / math.Pi
+		e := c.DrawLine(x, y, lineAngle, math.Hypot(dx, dy))
+		if e != nil {
+			return fmt.Errorf("Failed applying go-to instruction: %w", e)
+		}
+	}
+	s.moveTo(n.x, n.y)
+	return nil
+}
+
+// An instruction setting the turtle's absolute heading, in degrees.
+type setHeadingInstruction struct {
+	degrees float64
+}
+
+func (n *setHeadingInstruction) String() string {
+	return fmt.Sprintf("Set heading to %f degrees", n.degrees)
+}
+
+func (n *setHeadingInstruction) Apply(s *TurtleState, c Canvas) error {
+	s.position.angle = math.Mod(n.degrees, 360.0)
+	return nil
+}
+
+// An instruction that turns the turtle to face an absolute position. Facing
+// the turtle's own position leaves its heading unchanged.
+type faceTowardsInstruction struct {
+	x, y float64
+}
+
+func (n *faceTowardsInstruction) String() string {
+	return fmt.Sprintf("Face towards (%f, %f)", n.x, n.y)
+}
+
+func (n *faceTowardsInstruction) Apply(s *TurtleState, c Canvas) error {
+	x, y, _ := s.getPosition()
+	dx := n.x - x
+	dy := n.y - y
+	if (dx == 0) && (dy == 0) {
+		return nil
+	}
+	s.position.angle = normalizeDegrees(math.Atan2(dy, dx) * 180.0 / math.Pi)
+	return nil
+}
+
+// Sends the turtle back to the position and heading it started at. Draws a
+// line to the starting position if the pen is down.
+type homeInstruction struct{}
+
+func (n *homeInstruction) String() string {
+	return "Home"
+}
+
+func (n *homeInstruction) Apply(s *TurtleState, c Canvas) error {
+	goHome := goToInstruction{
+		x:    s.start.x,
+		y:    s.start.y,
+		draw: true,
+	}
+	e := goHome.Apply(s, c)
+	if e != nil {
+		return e
+	}
+	s.position.angle = s.start.angle
+	return nil
+}
+
+// Lifts or lowers the turtle's pen. While the pen is up, the turtle moves
+// without drawing anything on the canvas.
+type setPenInstruction struct {
+	up bool
+}
+
+func (n *setPenInstruction) String() string {
+	if n.up {
+		return "Pen up"
+	}
+	return "Pen down"
+}
+
+func (n *setPenInstruction) Apply(s *TurtleState, c Canvas) error {
+	s.position.penUp = n.up
 	return nil
 }
 
@@ -262,8 +447,8 @@ func (n *pushPositionInstruction) String() string {
 	return "Push position"
 }
 
-func (n *pushPositionInstruction) apply(t *Turtle, c Canvas) error {
-	t.positionStack = append(t.positionStack, t.position)
+func (n *pushPositionInstruction) Apply(s *TurtleState, c Canvas) error {
+	s.positionStack = append(s.positionStack, s.position)
 	return nil
 }
 
@@ -275,125 +460,422 @@ func (n *popPositionInstruction) String() string {
 	return "Pop position"
 }
 
-func (n *popPositionInstruction) apply(t *Turtle, c Canvas) error {
-	if len(t.positionStack) == 0 {
-		return fmt.Errorf("Can't pop the turtle's position: empty stack")
+func (n *popPositionInstruction) Apply(s *TurtleState, c Canvas) error {
+	if len(s.positionStack) == 0 {
+		return ErrEmptyStack
+	}
+	topIndex := len(s.positionStack) - 1
+	top := s.positionStack[topIndex]
+	styleChanged := (top.styleID != s.position.styleID) ||
+		(top.lineWidth != s.position.lineWidth)
+	// Line widths are scaled by the frame, so the style must be passed to
+	// the canvas again if the frame changes.
+	frameChanged := top.transform != s.position.transform
+	if frameChanged && (top.lineWidth > 0) {
+		styleChanged = true
+	}
+	// The popped position is in the popped frame.
+	s.position.transform = top.transform
+	s.moveTo(top.x, top.y)
+	s.position = top
+	s.positionStack = s.positionStack[0:topIndex]
+	if styleChanged {
+		return s.frameCanvas(c).SetStyle(s.canvasStyle())
 	}
-	topIndex := len(t.positionStack) - 1
-	t.position = t.positionStack[topIndex]
-	t.positionStack = t.positionStack[0:topIndex]
 	return nil
 }
 
-// Holds the turtle's x and y coordinate, as well as the angle it's facing.
+// Holds the turtle's x and y coordinate, as well as the angle it's facing,
+// and the state of its pen: whether it's up, its style, and the scale factors
+// applied to its steps and lines. The zero value has the pen down, but has a
+// step scale of 0, so initialPosition must be used to obtain a usable starting
+// position.
 type turtlePosition struct {
 	x, y, angle float64
+	penUp       bool
+	// Multiplies the distances the turtle moves.
+	stepScale float64
+	// The width of the lines the turtle draws, or 0 for the thinnest lines
+	// the canvas supports.
+	lineWidth float64
+	// The most recent style set by an instruction, or nil if none has been
+	// set.
+	style StrokeStyle
+	// Identifies the instruction that set the style, so PopPosition can tell
+	// whether the style changed without comparing styles, which may not be
+	// comparable. 0 if no style has been set.
+	styleID int
+	// The transformation from the turtle's frame to the canvas, or nil if the
+	// frame hasn't been transformed. Never modified once set, so positions
+	// may share it.
+	transform *AffineTransform
+}
+
+// Returns the position a turtle starts rendering at, given the turtle's
+// starting position and heading. The step scale is 1, the line width is 0, no
+// style is set and the frame isn't transformed, regardless of their values in
+// start.
+func initialPosition(start turtlePosition) turtlePosition {
+	start.stepScale = 1
+	start.lineWidth = 0
+	start.style = nil
+	start.styleID = 0
+	start.transform = nil
+	return start
 }
 
 func (p *turtlePosition) String() string {
-	return fmt.Sprintf("Turtle position: (%f, %f), facing %f degrees", p.x,
-		p.y, p.angle)
+	pen := "down"
+	if p.penUp {
+		pen = "up"
+	}
+	return fmt.Sprintf("Turtle position: (%f, %f), facing %f degrees, pen "+
+		"%s, step scale %f, line width %f", p.x, p.y, p.angle, pen,
+		p.stepScale, p.lineWidth)
 }
 
-// The "turtle" that moves around.
-type Turtle struct {
+// Holds the state of a turtle while its instructions are carried out. Passed
+// to Instruction.Apply, so that instructions can inspect and update the
+// turtle's position and heading. A new TurtleState is created for each call to
+// Turtle.RenderToCanvas, so it is only valid during a single rendering.
+type TurtleState struct {
 	// The turtle's current position.
 	position turtlePosition
 	// A stack of positions, that may be manipulated by instructions. Starts
-	// empty. Popping an empty stack is an error.
+	// empty. Popping an empty stack fails with ErrEmptyStack.
 	positionStack []turtlePosition
-	// The instructions the turtle must follow.
-	instructions []turtleInstruction
+	// The source of random numbers for randomized instructions. Seeded using
+	// the Turtle's seed at the start of each rendering.
+	random *rand.Rand
+	// The number of subprograms, such as those run by Turtle.Chance, that are
+	// currently being carried out. Used to detect runaway recursion.
+	subprogramDepth int
+	// The shape currently being filled. nil if no fill is in progress.
+	fill *fillState
+	// The position the turtle started at, which the Home instruction returns
+	// to.
+	start turtlePosition
+	// The number of styles set so far, used to give each one a different
+	// styleID.
+	styleCount int
+	// The procedure being carried out by CallRecursive, if any.
+	recursion recursionLevel
+	// The canvas returned by frameCanvas while the turtle's frame is
+	// transformed. nil until the frame is first transformed.
+	frame *transformCanvas
 }
 
 // Returns the x, y position of the turtle, followed by the angle it is facing.
-func (t *Turtle) getPosition() (float64, float64, float64) {
-	return t.position.x, t.position.y, t.position.angle
+func (s *TurtleState) getPosition() (float64, float64, float64) {
+	return s.position.x, s.position.y, s.position.angle
+}
+
+// Returns the turtle's current x, y position.
+func (s *TurtleState) Position() (float64, float64) {
+	return s.position.x, s.position.y
+}
+
+// Moves the turtle to the given x, y position without drawing anything. If
+// the turtle is filling a shape, the position is added to the shape's outline.
+func (s *TurtleState) SetPosition(x, y float64) {
+	s.moveTo(x, y)
+}
+
+// Updates the turtle's position, adding the new position to the outline of the
+// shape being filled, if any. All instructions that change the turtle's
+// position should use this.
+func (s *TurtleState) moveTo(x, y float64) {
+	s.position.x = x
+	s.position.y = y
+	if s.fill != nil {
+		s.fill.addPoint(s.framePoint(x, y))
+	}
+}
+
+// Returns the angle the turtle is facing, in degrees.
+func (s *TurtleState) Heading() float64 {
+	return s.position.angle
+}
+
+// Sets the angle the turtle is facing, in degrees.
+func (s *TurtleState) SetHeading(degrees float64) {
+	s.position.angle = math.Mod(degrees, 360.0)
+}
+
+// Returns true if the turtle's pen is down, meaning that moves are drawn.
+func (s *TurtleState) PenDown() bool {
+	return !s.position.penUp
+}
+
+// Returns the most recent style set by an instruction, or nil if none has been
+// set. The style passed to the canvas also includes the turtle's line width,
+// if it has one.
+func (s *TurtleState) Style() StrokeStyle {
+	return s.position.style
+}
+
+// Returns the factor by which the distances the turtle moves are multiplied.
+// See Turtle.ScaleStep.
+func (s *TurtleState) StepScale() float64 {
+	return s.position.stepScale
+}
+
+// Returns the width of the lines the turtle draws, or 0 if it draws the
+// thinnest lines the canvas supports. See Turtle.SetLineWidth.
+func (s *TurtleState) LineWidth() float64 {
+	return s.position.lineWidth
+}
+
+// Returns the number of positions on the turtle's position stack.
+func (s *TurtleState) StackDepth() int {
+	return len(s.positionStack)
+}
+
+// Returns the random number generator used by randomized instructions.
+// User-defined instructions may use it to make random choices that are the
+// same each time the turtle is rendered.
+func (s *TurtleState) Random() *rand.Rand {
+	return s.random
+}
+
+// Returns a new TurtleState at the given starting position, with an empty
+// position stack. Its random number generator is initialized using the given
+// seed.
+func newTurtleState(seed int64, start turtlePosition) *TurtleState {
+	return &TurtleState{
+		position:        initialPosition(start),
+		positionStack:   make([]turtlePosition, 0, 128),
+		random:          rand.New(rand.NewSource(seed)),
+		subprogramDepth: 0,
+		fill:            nil,
+		start:           start,
+		styleCount:      0,
+	}
+}
+
+func (s *TurtleState) String() string {
+	return fmt.Sprintf("%s, stack depth %d", s.position.String(),
+		len(s.positionStack))
+}
+
+// The "turtle" that moves around. A Turtle only records instructions; the
+// state of the turtle as it follows them is kept in a separate TurtleState for
+// each rendering. So, once a Turtle's instructions have been recorded, it's
+// safe to call RenderToCanvas from multiple goroutines at once, as long as the
+// Turtle isn't modified at the same time and each goroutine uses a different
+// Canvas.
+type Turtle struct {
+	// The instructions the turtle must follow, stored compactly.
+	instructions instructionList
+	// Used to seed the random number generator for randomized instructions at
+	// the start of each rendering.
+	seed int64
+	// Controls how angles passed to the turtle's methods are interpreted.
+	options TurtleOptions
+	// The turtle's position at the start of each rendering.
+	start turtlePosition
+	// If non-nil, instructions are carried out as soon as they're added
+	// instead of being stored. Only set for turtles created by
+	// RenderStreamContext.
+	stream *instructionStream
+}
+
+// Adds an instruction to the end of the turtle's list of instructions, or
+// carries it out immediately if the turtle is streaming. The object is
+// ignored if the opcode doesn't use one.
+func (t *Turtle) addOp(op opcode, object interface{}, operands ...float64) {
+	if t.stream != nil {
+		t.stream.run(op, operands, object)
+		return
+	}
+	t.instructions.add(op, object, operands...)
+}
+
+// Adds an arbitrary instruction to the turtle's list of instructions. This can
+// be used to add user-defined instructions, which will be carried out by
+// RenderToCanvas in order along with the built-in instructions. Like Insert,
+// nil instructions aren't allowed; Add ignores them.
+func (t *Turtle) Add(n Instruction) {
+	if n == nil {
+		return
+	}
+	op, object, operands := encodeInstruction(n)
+	t.addOp(op, object, operands...)
 }
 
 // Adds an instruction to move forward by the given distance to the turtle's
 // list of instructions.
 func (t *Turtle) MoveForward(distance float64) {
-	n := &moveForwardInstruction{
-		distance: distance,
-	}
-	t.instructions = append(t.instructions, n)
+	t.addOp(opMoveForward, nil, distance)
 }
 
 // Adds an instruction to turn by the given amount to the turtle's list of
-// instructions.
+// instructions. By default, the amount is in degrees, and positive turns are
+// counter-clockwise; see TurtleOptions.
 func (t *Turtle) Turn(degrees float64) {
-	n := &turnInstruction{
-		degrees: degrees,
-	}
-	t.instructions = append(t.instructions, n)
+	t.addOp(opTurn, nil, t.relativeAngle(degrees))
 }
 
 // Adds an instruction to change the stroke style to the turtle's list of
-// instructions.
+// instructions. The style is saved by PushPosition and restored by
+// PopPosition, so a branch may change it without affecting the rest of the
+// drawing.
 func (t *Turtle) SetStyle(style StrokeStyle) {
-	n := &setStyleInstruction{
-		style: style,
-	}
-	t.instructions = append(t.instructions, n)
+	t.addOp(opSetStyle, style)
 }
 
 // Adds an instruction for the turtle to move the given number of degrees along
 // an arc with the specified radius. The center of the circle defining the
-// arc will be 90 degrees to the turtle's left.
+// arc will be 90 degrees to the turtle's left, or to its right if the turtle
+// uses LogoOrientation, so that positive arcs turn in the same direction as
+// positive turns. The angle is in the turtle's AngleUnit.
 func (t *Turtle) MoveArc(radius, degrees float64) {
-	n := &moveArcInstruction{
-		radius:  radius,
-		degrees: degrees,
-	}
-	t.instructions = append(t.instructions, n)
+	t.addOp(opMoveArc, nil, t.leftDistance(radius), t.relativeAngle(degrees))
+}
+
+// Adds an instruction to move the turtle in a straight line to the absolute
+// position (x, y), drawing a line if the pen is down. Doesn't change the
+// direction the turtle is facing.
+func (t *Turtle) GoTo(x, y float64) {
+	t.addOp(opGoTo, nil, x, y)
 }
 
-// Adds an instruction to push the turtle's current position and orientation
-// onto the top of a stack of past positions and orientations.
+// Adds an instruction to move the turtle to the absolute position (x, y)
+// without drawing, regardless of whether the pen is up or down. Doesn't change
+// the direction the turtle is facing.
+func (t *Turtle) JumpTo(x, y float64) {
+	t.addOp(opJumpTo, nil, x, y)
+}
+
+// Adds an instruction to set the direction the turtle is facing to the given
+// absolute angle, in degrees. 0 degrees faces along the positive X axis, and
+// angles increase counter-clockwise, unless the turtle was created with
+// different TurtleOptions.
+func (t *Turtle) SetHeading(degrees float64) {
+	t.addOp(opSetHeading, nil, t.absoluteHeading(degrees))
+}
+
+// Adds an instruction to turn the turtle so that it faces the absolute
+// position (x, y). Has no effect if the turtle is already at (x, y).
+func (t *Turtle) FaceTowards(x, y float64) {
+	t.addOp(opFaceTowards, nil, x, y)
+}
+
+// Adds an instruction to return the turtle to the position and heading it
+// started at: the origin, facing 0 degrees, unless the turtle was created with
+// different TurtleOptions. A line to the starting position is drawn if the pen
+// is down.
+func (t *Turtle) Home() {
+	t.addOp(opHome, nil)
+}
+
+// Adds an instruction to lift the turtle's pen. Subsequent moves will change
+// the turtle's position without drawing, until PenDown is called.
+func (t *Turtle) PenUp() {
+	t.addOp(opPenUp, nil)
+}
+
+// Adds an instruction to lower the turtle's pen, so that subsequent moves are
+// drawn. The turtle's pen starts out down.
+func (t *Turtle) PenDown() {
+	t.addOp(opPenDown, nil)
+}
+
+// Adds an instruction to push the turtle's current position, orientation, and
+// pen state onto the top of a stack of past positions and orientations. The
+// pen state includes whether the pen is up, the stroke style, the step scale
+// and the line width.
 func (t *Turtle) PushPosition() {
-	n := &pushPositionInstruction{}
-	t.instructions = append(t.instructions, n)
+	t.addOp(opPushPosition, nil)
 }
 
 // Adds an instruction to set the turtle's position to whatever is on top of
 // the stack of past positions, removing the position from the stack in the
-// process.
+// process. If the style or line width changed since the position was pushed,
+// the style is passed to the canvas again, or black is used if no style had
+// been set. Rendering fails with ErrEmptyStack if the stack is empty.
 func (t *Turtle) PopPosition() {
-	n := &popPositionInstruction{}
-	t.instructions = append(t.instructions, n)
+	t.addOp(opPopPosition, nil)
+}
+
+// The default number of instructions RenderToCanvasContext carries out
+// between checking for cancellation and reporting progress.
+const DefaultCheckInterval = 4096
+
+// Options that control the behavior of RenderToCanvasContext. The zero value
+// uses the default for each option.
+type RenderOptions struct {
+	// If non-nil, this is called periodically during rendering with the number
+	// of instructions that have been carried out so far, and the total number
+	// of instructions. It is always called once all instructions are done.
+	// When rendering an InstructionSource, the total is -1 until the final
+	// call, since it isn't known in advance.
+	Progress func(done, total int)
+	// The number of instructions to carry out between checking whether the
+	// context has been canceled and calling Progress. DefaultCheckInterval is
+	// used if this is 0 or negative.
+	CheckInterval int
+	// The seed and options of the turtle passed to an InstructionSource, as if
+	// it had been created using NewTurtleWithOptions and SetSeed. Ignored when
+	// rendering a Turtle's stored instructions, which use its own seed and
+	// options.
+	Seed          int64
+	TurtleOptions TurtleOptions
 }
 
 // Carries out all of the turtle's stored instructions, writing the results to
-// the given canvas.
+// the given canvas. Doesn't modify the turtle, so it may be called
+// concurrently from multiple goroutines, provided each uses its own canvas. If
+// an instruction fails, returns an *InstructionError identifying it, which
+// wraps errors such as ErrEmptyStack. Use Validate to check for such errors
+// without rendering.
 func (t *Turtle) RenderToCanvas(c Canvas) error {
+	return t.RenderToCanvasContext(context.Background(), c, nil)
+}
+
+// Like RenderToCanvas, but periodically checks whether ctx has been canceled,
+// and stops rendering if so. In that case, the returned error wraps the
+// context's error, so errors.Is(e, context.Canceled) can be used to detect
+// cancellation. The opts may be nil to use default options.
+func (t *Turtle) RenderToCanvasContext(ctx context.Context, c Canvas,
+	opts *RenderOptions) error {
 	var e error
-	// Reset any remaining state from past renderings.
-	t.positionStack = t.positionStack[0:0]
-	t.position = turtlePosition{
-		x:     0,
-		y:     0,
-		angle: 0,
-	}
-	for i, n := range t.instructions {
-		e = n.apply(t, c)
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	checkInterval := opts.CheckInterval
+	if checkInterval <= 0 {
+		checkInterval = DefaultCheckInterval
+	}
+	total := t.instructions.len()
+	s := newTurtleState(t.seed, t.start)
+	for cursor := t.instructions.cursor(); cursor.valid(); cursor.next() {
+		i := cursor.index
+		if (i % checkInterval) == 0 {
+			e = ctx.Err()
+			if e != nil {
+				return fmt.Errorf("Rendering stopped after %d/%d "+
+					"instructions: %w", i, total, e)
+			}
+			if (opts.Progress != nil) && (i != 0) {
+				opts.Progress(i, total)
+			}
+		}
+		op, operands, object := cursor.get()
+		e = executeInstruction(s, c, op, operands, object)
 		if e != nil {
-			return fmt.Errorf("Error executing instruction %d/%d (%s): %w",
-				i+1, len(t.instructions), n.String(), e)
+			return newInstructionError(i, cursor.instruction(), e)
 		}
 	}
+	if opts.Progress != nil {
+		opts.Progress(total, total)
+	}
 	return nil
 }
 
 // Returns an initialized Turtle instance, with no instructions.
 func NewTurtle() *Turtle {
 	return &Turtle{
-		position: turtlePosition{
-			x:     0,
-			y:     0,
-			angle: 0,
-		},
-		positionStack: make([]turtlePosition, 0, 128),
-		instructions:  make([]turtleInstruction, 0, 4096),
+		instructions: newInstructionList(initialInstructionCapacity),
 	}
 }
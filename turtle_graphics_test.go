@@ -0,0 +1,215 @@
+package turtle_graphics
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"sync"
+	"testing"
+)
+
+// A user-defined instruction that moves the turtle sideways without drawing,
+// using the TurtleState rather than any of the turtle's own methods.
+type sidestepTestInstruction struct {
+	distance float64
+}
+
+func (n *sidestepTestInstruction) Apply(s *TurtleState, c Canvas) error {
+	x, y := s.Position()
+	x, y = moveDegrees(x, y, s.Heading()+90, n.distance)
+	s.SetPosition(x, y)
+	return nil
+}
+
+func (n *sidestepTestInstruction) String() string {
+	return fmt.Sprintf("Sidestep %f", n.distance)
+}
+
+// Returns a turtle using only the basic movement, pen and position stack
+// instructions, along with a user-defined instruction.
+func getBasicConcurrencyTestTurtle() *Turtle {
+	t := NewTurtle()
+	for i := 0; i < 36; i++ {
+		t.SetStyle(GetColorStyle(color.RGBA{uint8(i * 7), 0, 0x80, 0xff}))
+		t.PushPosition()
+		t.MoveForward(float64(i%5) + 1)
+		t.MoveArc(0.5, 180)
+		t.PenUp()
+		t.Add(&sidestepTestInstruction{distance: 0.25})
+		t.PenDown()
+		t.FaceTowards(0, 0)
+		t.MoveForward(1)
+		t.PopPosition()
+		t.Turn(10)
+	}
+	t.JumpTo(-8, -8)
+	t.GoTo(8, -8)
+	t.Home()
+	t.SetHeading(45)
+	t.MoveForward(3)
+	return t
+}
+
+// Holds the results of rendering a turtle to both kinds of canvas.
+type basicRenderResult struct {
+	extents [4]float64
+	png     []byte
+	err     error
+}
+
+// Renders the turtle to a DummyCanvas and to a PNG image of the given height.
+func renderBasicConcurrencyTestTurtle(t *Turtle,
+	pixelsTall int) basicRenderResult {
+	var result basicRenderResult
+	c := NewDummyCanvas()
+	result.err = t.RenderToCanvas(c)
+	if result.err != nil {
+		return result
+	}
+	minX, minY, maxX, maxY := c.GetExtents()
+	result.extents = [4]float64{minX, minY, maxX, maxY}
+	var b bytes.Buffer
+	result.err = SaveTurtleAsPNG(t, pixelsTall, &b)
+	result.png = b.Bytes()
+	return result
+}
+
+// Renders a single turtle to a DummyCanvas and to PNG images of several sizes
+// from several goroutines at once, checking that each result matches
+// rendering the turtle alone. Run with -race to check that rendering doesn't
+// modify the turtle.
+func TestConcurrentRenderingBasic(t *testing.T) {
+	turtle := getBasicConcurrencyTestTurtle()
+	sizes := []int{50, 100, 150, 200}
+	expected := make([]basicRenderResult, len(sizes))
+	for i, size := range sizes {
+		expected[i] = renderBasicConcurrencyTestTurtle(turtle, size)
+		if expected[i].err != nil {
+			t.Fatalf("Failed rendering test turtle: %s", expected[i].err)
+		}
+	}
+	const goroutines = 8
+	results := make([]basicRenderResult, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			size := sizes[i%len(sizes)]
+			results[i] = renderBasicConcurrencyTestTurtle(turtle, size)
+		}(i)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if result.err != nil {
+			t.Errorf("Goroutine %d failed rendering: %s", i, result.err)
+			continue
+		}
+		reference := expected[i%len(sizes)]
+		if result.extents != reference.extents {
+			t.Errorf("Goroutine %d got extents %v, expected %v", i,
+				result.extents, reference.extents)
+		}
+		if !bytes.Equal(result.png, reference.png) {
+			t.Errorf("Goroutine %d rendered a different image", i)
+		}
+	}
+}
+
+// Returns a turtle using subprograms, random choices, fills, line widths and
+// transformed frames, so that rendering it touches as much shared state as
+// possible.
+func getConcurrencyTestTurtle() *Turtle {
+	square := NewTurtle()
+	for i := 0; i < 4; i++ {
+		square.MoveForward(1)
+		square.Turn(90)
+	}
+	branch := NewTurtle()
+	branch.SetStyle(GetColorStyle(color.RGBA{0, 0x80, 0, 0xff}))
+	branch.MoveForward(2)
+	branch.Stamp(square)
+	koch := NewTurtle()
+	koch.Recurse(1.0 / 3)
+	koch.Turn(60)
+	koch.Recurse(1.0 / 3)
+	koch.Turn(-120)
+	koch.Recurse(1.0 / 3)
+	koch.Turn(60)
+	koch.Recurse(1.0 / 3)
+	segment := NewTurtle()
+	segment.MoveForward(1)
+
+	t := NewTurtle()
+	t.SetSeed(1337)
+	t.SetLineWidth(0.2)
+	for i := 0; i < 12; i++ {
+		t.PushPosition()
+		t.TurnRandom(-20, 20)
+		t.Chance(0.5, branch)
+		t.Scale(1.5, 0.75)
+		t.BeginFill(GetColorStyle(color.RGBA{0xff, 0, 0, 0xff}),
+			EvenOddFill)
+		t.Call(square, 2)
+		t.MoveArc(1, 270)
+		t.EndFill()
+		t.Mirror()
+		t.CallRecursive(koch, segment, 3, 4)
+		t.PopPosition()
+		t.Turn(30)
+		t.MoveForwardRandom(0.5, 1.5)
+	}
+	return t
+}
+
+// Renders the turtle to a new RGBACanvas, returning the canvas's pixels.
+func renderConcurrencyTestTurtle(t *Turtle) ([]byte, error) {
+	c, e := NewRGBACanvas(200, 200, -20, -20, 20, 20, color.White)
+	if e != nil {
+		return nil, e
+	}
+	e = t.RenderToCanvas(c)
+	if e != nil {
+		return nil, e
+	}
+	return c.pic.Pix, nil
+}
+
+// Renders a single turtle from several goroutines at once, checking that each
+// produces the same image as rendering it alone. Run with -race to check that
+// rendering doesn't modify the turtle.
+func TestConcurrentRendering(t *testing.T) {
+	turtle := getConcurrencyTestTurtle()
+	e := turtle.Validate()
+	if e != nil {
+		t.Fatalf("Test turtle is invalid: %s", e)
+	}
+	expected, e := renderConcurrencyTestTurtle(turtle)
+	if e != nil {
+		t.Fatalf("Failed rendering test turtle: %s", e)
+	}
+	if bytes.Count(expected, []byte{0xff}) == len(expected) {
+		t.Fatalf("Test turtle didn't draw anything")
+	}
+	const goroutines = 8
+	results := make([][]byte, goroutines)
+	errors := make([]error, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errors[i] = renderConcurrencyTestTurtle(turtle)
+		}(i)
+	}
+	wg.Wait()
+	for i := range results {
+		if errors[i] != nil {
+			t.Errorf("Goroutine %d failed rendering: %s", i, errors[i])
+			continue
+		}
+		if !bytes.Equal(results[i], expected) {
+			t.Errorf("Goroutine %d rendered a different image", i)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package turtle_graphics
+
+// This file contains the errors returned when a turtle's instructions can't
+// be carried out, and a pass that checks for them without rendering.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Returned when popping the turtle's position while the stack is empty.
+var ErrEmptyStack = errors.New("Can't pop the turtle's position: empty " +
+	"stack")
+
+// Returned by Validate if a pushed position is never popped.
+var ErrUnpoppedPosition = errors.New("The pushed position is never popped")
+
+// Returned when beginning a fill while another fill is in progress.
+var ErrFillInProgress = errors.New("Can't begin a fill while another is in " +
+	"progress")
+
+// Returned when ending a fill that hasn't begun.
+var ErrNoFill = errors.New("Can't end a fill that hasn't begun")
+
+// Returned by Validate if a fill is never ended.
+var ErrUnclosedFill = errors.New("The fill is never ended")
+
+// Returned by Validate if an instruction has a NaN or infinite operand.
+var ErrInvalidOperand = errors.New("Operands must be finite numbers")
+
+// Returned by Validate if an instruction's subprogram, such as the shape
+// drawn by Stamp, is nil.
+var ErrNilSubprogram = errors.New("The subprogram is nil")
+
+// Returned by Validate if a subprogram, such as the turtle run by Call, uses
+// itself, either directly or through other subprograms. Use CallRecursive to
+// draw a turtle recursively.
+var ErrRecursiveSubprogram = errors.New("A subprogram can't use itself")
+
+// Returned by Recurse if it isn't part of a procedure carried out by
+// CallRecursive.
+var ErrNoProcedure = errors.New("Recurse can only be used in a procedure " +
+	"carried out by CallRecursive")
+
+// Identifies the instruction that caused an error, either while rendering or
+// in Validate. Err may be one of the sentinel errors in this package, such as
+// ErrEmptyStack, or an error returned by a canvas or a user-defined
+// instruction. If the failing instruction runs a subprogram, Err may be
+// another *InstructionError identifying the instruction in the subprogram.
+type InstructionError struct {
+	// The index of the instruction in the turtle's list of instructions,
+	// starting at 0.
+	Index int
+	// The kind of instruction that failed.
+	Kind InstructionKind
+	// The instruction that failed.
+	Instruction Instruction
+	// The reason the instruction failed.
+	Err error
+}
+
+func (e *InstructionError) Error() string {
+	return fmt.Sprintf("Instruction %d (%s): %s", e.Index,
+		e.Instruction.String(), e.Err)
+}
+
+func (e *InstructionError) Unwrap() error {
+	return e.Err
+}
+
+// Returns an *InstructionError for the given instruction.
+func newInstructionError(index int, n Instruction,
+	e error) *InstructionError {
+	return &InstructionError{
+		Index:       index,
+		Kind:        describeInstruction(n).Kind,
+		Instruction: n,
+		Err:         e,
+	}
+}
+
+// Holds the state needed while validating a single list of instructions.
+type validator struct {
+	// The indices of the pushes that haven't been popped yet.
+	pushes []int
+	// The index of the fill in progress, or -1 if there is none.
+	fill int
+	// Set if a fill is in progress when the list starts, which is only
+	// possible for a subprogram.
+	outerFill bool
+	// Set if the list is part of a procedure carried out by CallRecursive,
+	// meaning that it may use Recurse.
+	inProcedure bool
+	// The turtles whose instructions are being validated, used to avoid
+	// validating subprograms that contain themselves more than once.
+	active map[*Turtle]bool
+}
+
+// Checks a single instruction, returning an error if it's invalid.
+func (v *validator) check(index int, op opcode, operands []float64,
+	object interface{}) error {
+	for _, x := range operands {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return ErrInvalidOperand
+		}
+	}
+	switch op {
+	case opPushPosition:
+		v.pushes = append(v.pushes, index)
+	case opPopPosition:
+		if len(v.pushes) == 0 {
+			return ErrEmptyStack
+		}
+		v.pushes = v.pushes[0 : len(v.pushes)-1]
+	case opBeginFill:
+		if (v.fill >= 0) || v.outerFill {
+			return ErrFillInProgress
+		}
+		v.fill = index
+	case opEndFill:
+		if v.fill < 0 {
+			return ErrNoFill
+		}
+		v.fill = -1
+	case opChance:
+		// The subprogram may or may not run, so it must leave the stack and
+		// fill as it found them.
+		subprogram, _ := object.(*Turtle)
+		return v.validateSubprogram(subprogram, (v.fill >= 0) || v.outerFill,
+			v.inProcedure)
+	case opStamp, opCall:
+		// Shapes and called turtles are drawn with their own stack, and
+		// without a fill.
+		subprogram, _ := object.(*Turtle)
+		return v.validateSubprogram(subprogram, false, v.inProcedure)
+	case opCallRecursive:
+		p, _ := object.(*procedure)
+		if p == nil {
+			return ErrNilSubprogram
+		}
+		e := v.validateSubprogram(p.body, false, true)
+		if (e != nil) || (p.base == nil) {
+			return e
+		}
+		return v.validateSubprogram(p.base, false, false)
+	case opRecurse:
+		if !v.inProcedure {
+			return ErrNoProcedure
+		}
+	}
+	return nil
+}
+
+// Validates a subprogram's instructions on their own, given whether a fill
+// is in progress when it starts and whether it's part of a procedure.
+func (v *validator) validateSubprogram(t *Turtle, outerFill,
+	inProcedure bool) error {
+	if t == nil {
+		return ErrNilSubprogram
+	}
+	// Subprograms started by Chance, Stamp or Call have no depth limit of
+	// their own, so one that starts itself would never finish.
+	if v.active[t] {
+		return ErrRecursiveSubprogram
+	}
+	sub := &validator{
+		pushes:      nil,
+		fill:        -1,
+		outerFill:   outerFill,
+		inProcedure: inProcedure,
+		active:      v.active,
+	}
+	return sub.validate(t)
+}
+
+// Checks each of the turtle's instructions in order, returning an
+// *InstructionError for the first invalid one.
+func (v *validator) validate(t *Turtle) error {
+	v.active[t] = true
+	defer delete(v.active, t)
+	list := &(t.instructions)
+	for cursor := list.cursor(); cursor.valid(); cursor.next() {
+		op, operands, object := cursor.get()
+		e := v.check(cursor.index, op, operands, object)
+		if e != nil {
+			return newInstructionError(cursor.index, cursor.instruction(), e)
+		}
+	}
+	// Report whichever of an unclosed fill or an unpopped position comes
+	// first.
+	index, e := -1, error(nil)
+	if len(v.pushes) != 0 {
+		index, e = v.pushes[0], ErrUnpoppedPosition
+	}
+	if (v.fill >= 0) && ((index < 0) || (v.fill < index)) {
+		index, e = v.fill, ErrUnclosedFill
+	}
+	if e != nil {
+		cursor := list.cursorAt(index)
+		return newInstructionError(index, cursor.instruction(), e)
+	}
+	return nil
+}
+
+// Checks the turtle's instructions for problems without rendering them. Returns
+// nil if there are none, or an *InstructionError for the first problem found
+// otherwise. The InstructionError wraps one of ErrEmptyStack,
+// ErrUnpoppedPosition, ErrFillInProgress, ErrNoFill, ErrUnclosedFill,
+// ErrInvalidOperand, ErrNilSubprogram, ErrRecursiveSubprogram or
+// ErrNoProcedure, so callers may check for specific problems using errors.Is.
+// The subprograms used by Chance, Stamp, Call and CallRecursive are checked
+// too, and must leave the position stack and any fill as they found them. Apart
+// from the procedures carried out by CallRecursive, which use Recurse,
+// subprograms may not use themselves. User-defined instructions can't be
+// checked, and are assumed to be valid. Turtles streaming instructions from an
+// InstructionSource don't store them, so they are always valid.
+func (t *Turtle) Validate() error {
+	v := &validator{
+		pushes:      make([]int, 0, 128),
+		fill:        -1,
+		outerFill:   false,
+		inProcedure: false,
+		active:      make(map[*Turtle]bool),
+	}
+	return v.validate(t)
+}
@@ -0,0 +1,134 @@
+package turtle_graphics
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+)
+
+// Checks that Validate reports the given error, wrapped in an
+// *InstructionError for the instruction with the given index.
+func checkValidateError(t *testing.T, turtle *Turtle, index int,
+	expected error) {
+	e := turtle.Validate()
+	if e == nil {
+		t.Fatalf("Didn't get an error validating the turtle")
+	}
+	t.Logf("Got expected error: %s", e)
+	if !errors.Is(e, expected) {
+		t.Errorf("Expected %q, got %q", expected, e)
+	}
+	var instructionError *InstructionError
+	if !errors.As(e, &instructionError) {
+		t.Fatalf("Expected an *InstructionError, got %T", e)
+	}
+	if instructionError.Index != index {
+		t.Errorf("Expected the error at instruction %d, got %d", index,
+			instructionError.Index)
+	}
+}
+
+func TestValidateNilSubprograms(t *testing.T) {
+	// The public API ignores nil subprograms, so add them directly.
+	ops := []opcode{opStamp, opCall, opChance}
+	for _, op := range ops {
+		turtle := NewTurtle()
+		turtle.MoveForward(1)
+		operands := make([]float64, opcodeSizes[op].operands)
+		turtle.instructions.add(op, (*Turtle)(nil), operands...)
+		checkValidateError(t, turtle, 1, ErrNilSubprogram)
+		c, e := NewRGBACanvas(10, 10, -1, -1, 1, 1, color.White)
+		if e != nil {
+			t.Fatalf("Failed creating canvas: %s", e)
+		}
+		// Chance only runs its subprogram if its probability is above 0.
+		if op == opChance {
+			turtle = NewTurtle()
+			turtle.instructions.add(op, (*Turtle)(nil), 1)
+		}
+		e = turtle.RenderToCanvas(c)
+		if !errors.Is(e, ErrNilSubprogram) {
+			t.Errorf("Expected %q rendering, got %q", ErrNilSubprogram, e)
+		}
+	}
+}
+
+func TestValidateRecursiveSubprograms(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.MoveForward(1)
+	turtle.Call(turtle, 0.5)
+	checkValidateError(t, turtle, 1, ErrRecursiveSubprogram)
+
+	// Cycles through other subprograms must be found too.
+	a := NewTurtle()
+	b := NewTurtle()
+	a.Stamp(b)
+	b.Chance(0.5, a)
+	turtle = NewTurtle()
+	turtle.Turn(90)
+	turtle.Call(a, 1)
+	checkValidateError(t, turtle, 1, ErrRecursiveSubprogram)
+
+	// Using the same subprogram more than once isn't recursion.
+	a = NewTurtle()
+	a.MoveForward(1)
+	b = NewTurtle()
+	b.Stamp(a)
+	b.Call(a, 1)
+	turtle = NewTurtle()
+	turtle.Stamp(b)
+	turtle.Call(b, 2)
+	e := turtle.Validate()
+	if e != nil {
+		t.Errorf("Got unexpected error validating turtle: %s", e)
+	}
+}
+
+func TestValidateNilProcedure(t *testing.T) {
+	turtle := NewTurtle()
+	turtle.CallRecursive(nil, NewTurtle(), 3, 1)
+	if turtle.Len() != 0 {
+		t.Errorf("CallRecursive added an instruction with a nil body")
+	}
+	// The base may be nil, but the body may not.
+	turtle.instructions.add(opCallRecursive, &procedure{base: NewTurtle()},
+		3, 1)
+	checkValidateError(t, turtle, 0, ErrNilSubprogram)
+	c, e := NewRGBACanvas(10, 10, -1, -1, 1, 1, color.White)
+	if e != nil {
+		t.Fatalf("Failed creating canvas: %s", e)
+	}
+	e = turtle.RenderToCanvas(c)
+	if !errors.Is(e, ErrNilSubprogram) {
+		t.Errorf("Expected %q rendering, got %q", ErrNilSubprogram, e)
+	}
+}
+
+func TestValidateNestedChanceFill(t *testing.T) {
+	// The innermost subprogram begins a fill while the outer turtle's fill is
+	// in progress, since Chance runs subprograms without ending the fill.
+	inner := NewTurtle()
+	inner.BeginFill(GetColorStyle(color.Black), NonZeroFill)
+	inner.MoveForward(1)
+	inner.EndFill()
+	middle := NewTurtle()
+	middle.MoveForward(1)
+	middle.Chance(1, inner)
+	turtle := NewTurtle()
+	turtle.BeginFill(GetColorStyle(color.Black), NonZeroFill)
+	turtle.Chance(1, middle)
+	turtle.EndFill()
+	checkValidateError(t, turtle, 1, ErrFillInProgress)
+	e := turtle.RenderToCanvas(NewDummyCanvas())
+	if !errors.Is(e, ErrFillInProgress) {
+		t.Errorf("Expected %q rendering, got %q", ErrFillInProgress, e)
+	}
+
+	// Without the outer fill, the same subprograms are valid.
+	turtle = NewTurtle()
+	turtle.Chance(1, middle)
+	e = turtle.Validate()
+	if e != nil {
+		t.Errorf("Got unexpected error validating turtle: %s", e)
+	}
+}